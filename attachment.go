@@ -0,0 +1,129 @@
+package twist
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// Attachment is a file or a link attached to a thread, comment, or message.
+// Upload files with Client.UploadAttachment to get an Attachment to use with
+// NewThread, NewComment, or NewConversationMessage.
+type Attachment struct {
+	Id             string `json:"attachment_id"`
+	Title          string `json:"title,omitempty"`
+	URL            string `json:"url,omitempty"`
+	URLType        string `json:"url_type,omitempty"`
+	FileName       string `json:"file_name,omitempty"`
+	FileSize       int64  `json:"file_size,omitempty"`
+	UnderlyingType string `json:"underlying_type,omitempty"` // MIME type
+	UploadState    string `json:"upload_state,omitempty"`
+	Image          string `json:"image,omitempty"`
+	ImageWidth     int    `json:"image_width,omitempty"`
+	ImageHeight    int    `json:"image_height,omitempty"`
+}
+
+// UploadAttachment uploads file read from r and returns the resulting
+// Attachment. File is read into memory in full before upload, so that
+// request can be retried. If contentType is empty,
+// "application/octet-stream" is used.
+func (c *Client) UploadAttachment(ctx context.Context, r io.Reader, filename, contentType string) (*Attachment, error) {
+	if filename == "" {
+		return nil, errors.New("empty file name")
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	attachmentID, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("attachment_id", attachmentID); err != nil {
+		return nil, err
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file_name"; filename=%q`, filename))
+	h.Set(headerContentType, contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	const endpoint = "v3/attachments/upload"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+endpoint, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(headerContentType, mw.FormDataContentType())
+	var out Attachment
+	if err := c.doJSON(endpoint, req, false, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DownloadAttachment returns a stream of attachment file contents, which
+// caller must close. Failed requests are retried as Client's RetryPolicy
+// decides.
+//
+// Client's token is only sent along with request if attachment URL is on
+// the same host as Client's API base URL, or is an https URL on twist.com
+// domain.
+func (c *Client) DownloadAttachment(ctx context.Context, a Attachment) (io.ReadCloser, error) {
+	if a.URL == "" {
+		return nil, errors.New("attachment has no url")
+	}
+	u, err := url.Parse(a.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported attachment url scheme %q", u.Scheme)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.isTrustedHost(u) {
+		c.setHeaders(req)
+	} else if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return c.doRequestWithRetries("attachment", req, false, true)
+}
+
+// isTrustedHost reports whether host of u is allowed to receive Client's
+// token.
+func (c *Client) isTrustedHost(u *url.URL) bool {
+	if host := u.Hostname(); host == "twist.com" || strings.HasSuffix(host, ".twist.com") {
+		return u.Scheme == "https"
+	}
+	base, err := url.Parse(c.baseURL)
+	return err == nil && base.Scheme == u.Scheme && base.Host == u.Host
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
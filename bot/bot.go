@@ -0,0 +1,319 @@
+// Package bot implements command-driven Twist bots.
+//
+// Bot parses commands out of thread comments and conversation messages,
+// routes them to registered handlers, and lets handlers reply to the same
+// thread or conversation. A command is either a message starting with a
+// slash, like "/status", or a message addressing the bot by name or mention,
+// like "@bot deploy service"; words following command name become its
+// arguments.
+//
+// Typical usage:
+//
+//	client := twist.New(os.Getenv("TWIST_TOKEN"))
+//	b := bot.New(client, bot.WithName("bot"))
+//	b.Use(bot.AllowGroups(opsGroupID))
+//	b.Handle("deploy", func(ctx context.Context, r *bot.Request) error {
+//		if len(r.Args) != 1 {
+//			return r.Reply(ctx, "usage: deploy SERVICE")
+//		}
+//		return r.Reply(ctx, "Deploying "+r.Args[0]+"…")
+//	})
+//	http.ListenAndServe(addr, b.Handler(os.Getenv("TWIST_VERIFY_TOKEN")))
+//
+// Use twisttest.Server to test bots: feed messages to Bot.HandleMessage, then
+// inspect replies with Server.Comments and Server.ConversationMessages.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/webhook"
+)
+
+// ErrNotAllowed is returned by authorization middleware when a message author
+// is not allowed to run a command. Bot replies to such commands with
+// a refusal instead of a generic error message.
+var ErrNotAllowed = errors.New("not allowed")
+
+// HandlerFunc handles a single command.
+type HandlerFunc func(context.Context, *Request) error
+
+// Middleware wraps HandlerFunc, i.e. to check whether command is allowed.
+type Middleware func(HandlerFunc) HandlerFunc
+
+// Message is an incoming thread comment or conversation message. Exactly one
+// of ThreadID and ConversationID is expected to be set.
+type Message struct {
+	Text        string
+	UserID      uint64 // message author
+	UserName    string
+	WorkspaceID uint64
+
+	ThreadID       uint64 // set for thread comments and new threads
+	ConversationID uint64 // set for conversation messages
+}
+
+// Command is a parsed bot command.
+type Command struct {
+	Name string   // command name without leading slash, i.e. "deploy"
+	Args []string // whitespace-separated arguments, double quotes group words
+	Text string   // unparsed text following command name
+}
+
+// Request is passed to HandlerFunc, it holds the message and command parsed
+// out of it.
+type Request struct {
+	Message
+	Command
+
+	client *twist.Client
+}
+
+// Client returns client that bot uses to talk to Twist API.
+func (r *Request) Client() *twist.Client { return r.client }
+
+// Reply posts text to the thread or conversation the message came from.
+func (r *Request) Reply(ctx context.Context, text string) error {
+	switch {
+	case r.ThreadID != 0:
+		_, err := r.client.AddComment(ctx, twist.NewComment{ThreadID: r.ThreadID, Content: text})
+		return err
+	case r.ConversationID != 0:
+		_, err := r.client.AddConversationMessage(ctx, twist.NewConversationMessage{
+			ConversationID: r.ConversationID,
+			Content:        text,
+		})
+		return err
+	}
+	return errors.New("message has neither thread nor conversation id")
+}
+
+// Bot routes commands to handlers. Create it with New. Register handlers and
+// middleware before Bot starts handling messages.
+type Bot struct {
+	// ErrorLog, if set, is used to log errors returned by handlers.
+	ErrorLog *log.Logger
+
+	client     *twist.Client
+	name       string
+	userID     uint64
+	mentionRe  *regexp.Regexp
+	handlers   map[string]HandlerFunc
+	middleware []Middleware
+}
+
+// Option configures Bot.
+type Option func(*Bot)
+
+// WithName makes Bot recognize commands addressed to it as "@name command".
+func WithName(name string) Option { return func(b *Bot) { b.name = name } }
+
+// WithUserID makes Bot recognize commands starting with a Twist mention of
+// a given user, which is how "@bot" appears when picked from the mentions
+// list. Bot ignores messages posted by this user, which are its own replies.
+func WithUserID(id uint64) Option {
+	return func(b *Bot) {
+		b.userID = id
+		b.mentionRe = regexp.MustCompile(`^\[[^]]*\]\(twist-mention://` + strconv.FormatUint(id, 10) + `\)`)
+	}
+}
+
+// New returns Bot that uses client to reply to commands. Without options Bot
+// only recognizes slash commands.
+func New(client *twist.Client, opts ...Option) *Bot {
+	b := &Bot{client: client, handlers: make(map[string]HandlerFunc)}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Handle registers handler for a command. Command name is matched case
+// insensitively, leading slash is optional: "/status" and "status" register
+// the same command.
+func (b *Bot) Handle(name string, h HandlerFunc) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	if name == "" || h == nil {
+		panic("bot: Handle called with empty name or nil handler")
+	}
+	b.handlers[name] = h
+}
+
+// Use adds middleware applied to all commands. Middleware added first is
+// called first.
+func (b *Bot) Use(mw ...Middleware) { b.middleware = append(b.middleware, mw...) }
+
+// Parse extracts command from text. It reports false if text is not
+// a command addressed to the bot.
+func (b *Bot) Parse(text string) (Command, bool) {
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, "/"):
+		text = text[1:]
+	case b.name != "" && hasWordPrefix(text, "@"+b.name):
+		text = strings.TrimLeft(text[len(b.name)+1:], ":,")
+	case b.mentionRe != nil && b.mentionRe.MatchString(text):
+		text = strings.TrimSpace(b.mentionRe.ReplaceAllString(text, ""))
+	default:
+		return Command{}, false
+	}
+	name, rest := splitFirstField(text)
+	if name == "" {
+		return Command{}, false
+	}
+	rest = strings.TrimSpace(rest)
+	return Command{Name: strings.ToLower(name), Args: splitArgs(rest), Text: rest}, true
+}
+
+// HandleMessage parses command out of a message and calls its handler.
+// Messages that are not commands, and messages posted by Bot itself (see
+// WithUserID), are ignored. Unknown commands, commands
+// rejected with ErrNotAllowed and failed commands get a short reply;
+// HandleMessage only returns an error if that reply fails.
+func (b *Bot) HandleMessage(ctx context.Context, m Message) error {
+	if b.userID != 0 && m.UserID == b.userID {
+		return nil
+	}
+	cmd, ok := b.Parse(m.Text)
+	if !ok {
+		return nil
+	}
+	r := &Request{Message: m, Command: cmd, client: b.client}
+	h, ok := b.handlers[cmd.Name]
+	if !ok {
+		return r.Reply(ctx, fmt.Sprintf("Unknown command %q.", cmd.Name))
+	}
+	for _, mw := range slices.Backward(b.middleware) {
+		h = mw(h)
+	}
+	err := h(ctx, r)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrNotAllowed):
+		return r.Reply(ctx, fmt.Sprintf("You are not allowed to run %q.", cmd.Name))
+	}
+	if b.ErrorLog != nil {
+		b.ErrorLog.Printf("twist bot command %q from user %d: %v", cmd.Name, m.UserID, err)
+	}
+	return r.Reply(ctx, fmt.Sprintf("Command %q failed.", cmd.Name))
+}
+
+// Handler returns webhook.Handler that passes new threads, comments, and
+// conversation messages to HandleMessage. Replies are posted with the API
+// client, not as synchronous webhook replies.
+func (b *Bot) Handler(verifyToken string) *webhook.Handler {
+	h := webhook.NewHandler(verifyToken)
+	h.ErrorLog = b.ErrorLog
+	h.OnThread(func(ctx context.Context, e *webhook.ThreadEvent) (*webhook.Reply, error) {
+		return nil, b.HandleMessage(ctx, eventMessage(e.Event, e.Thread.Text, e.Thread.Id, 0))
+	})
+	h.OnComment(func(ctx context.Context, e *webhook.CommentEvent) (*webhook.Reply, error) {
+		return nil, b.HandleMessage(ctx, eventMessage(e.Event, e.Comment.Text, e.Comment.ThreadID, 0))
+	})
+	h.OnMessage(func(ctx context.Context, e *webhook.MessageEvent) (*webhook.Reply, error) {
+		return nil, b.HandleMessage(ctx, eventMessage(e.Event, e.Message.Text, 0, e.Message.ConversationID))
+	})
+	return h
+}
+
+func eventMessage(e webhook.Event, text string, threadID, conversationID uint64) Message {
+	return Message{
+		Text:           text,
+		UserID:         e.UserID,
+		UserName:       e.UserName,
+		WorkspaceID:    e.WorkspaceID,
+		ThreadID:       threadID,
+		ConversationID: conversationID,
+	}
+}
+
+// AllowUsers returns middleware that rejects commands from users other than
+// given ones with ErrNotAllowed.
+func AllowUsers(userIDs ...uint64) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *Request) error {
+			if !slices.Contains(userIDs, r.UserID) {
+				return ErrNotAllowed
+			}
+			return next(ctx, r)
+		}
+	}
+}
+
+// AllowGroups returns middleware that rejects commands from users who are not
+// members of any of given groups with ErrNotAllowed. Group members are
+// fetched on each command, so changes in groups apply immediately.
+func AllowGroups(groupIDs ...uint64) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *Request) error {
+			for _, id := range groupIDs {
+				g, err := r.Client().Group(ctx, id)
+				if err != nil {
+					return fmt.Errorf("fetching group %d: %w", id, err)
+				}
+				if slices.Contains(g.Members, r.UserID) {
+					return next(ctx, r)
+				}
+			}
+			return ErrNotAllowed
+		}
+	}
+}
+
+// hasWordPrefix reports whether s starts with prefix (case insensitively)
+// followed by a space or end of string.
+func hasWordPrefix(s, prefix string) bool {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return false
+	}
+	rest := s[len(prefix):]
+	return rest == "" || unicode.IsSpace(rune(rest[0])) || rest[0] == ':' || rest[0] == ','
+}
+
+// splitFirstField splits s into its first whitespace-separated field and the
+// rest of the string.
+func splitFirstField(s string) (string, string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// splitArgs splits s on whitespace, keeping words enclosed in double quotes
+// together. Unterminated quote extends to the end of string.
+func splitArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	var inQuotes, inArg bool
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/twisttest"
+)
+
+func TestBot_Parse(t *testing.T) {
+	b := New(nil, WithName("bot"), WithUserID(42))
+	for _, tc := range []struct {
+		text string
+		want Command
+		ok   bool
+	}{
+		{text: "/status", want: Command{Name: "status"}, ok: true},
+		{text: " /Status  now ", want: Command{Name: "status", Args: []string{"now"}, Text: "now"}, ok: true},
+		{text: "@bot deploy service", want: Command{Name: "deploy", Args: []string{"service"}, Text: "service"}, ok: true},
+		{text: "@Bot: deploy", want: Command{Name: "deploy"}, ok: true},
+		{text: "[Bot](twist-mention://42) deploy \"my service\" now", ok: true, want: Command{
+			Name: "deploy",
+			Args: []string{"my service", "now"},
+			Text: "\"my service\" now",
+		}},
+		{text: "@botany deploy"},
+		{text: "[Bot](twist-mention://43) deploy"},
+		{text: "hello /status"},
+		{text: "/"},
+		{text: "@bot"},
+	} {
+		got, ok := b.Parse(tc.text)
+		if ok != tc.ok || got.Name != tc.want.Name || got.Text != tc.want.Text || !slices.Equal(got.Args, tc.want.Args) {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v, %v", tc.text, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestBot_HandleMessage(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	tid := srv.AddThread(ch, twist.Thread{Title: "Deploys"})
+	conv := srv.AddConversation(ws, twist.Conversation{})
+	ops := srv.AddGroup(ws, twist.Group{Name: "Ops", Members: []uint64{1}})
+
+	b := New(srv.Client(), WithName("bot"))
+	b.Handle("/status", func(ctx context.Context, r *Request) error {
+		return r.Reply(ctx, "all good")
+	})
+	b.Handle("deploy", AllowGroups(ops)(func(ctx context.Context, r *Request) error {
+		return r.Reply(ctx, "deploying "+strings.Join(r.Args, ","))
+	}))
+	b.Handle("fail", func(context.Context, *Request) error { return errors.New("boom") })
+
+	ctx := context.Background()
+	for _, m := range []Message{
+		{Text: "/status", UserID: 2, ThreadID: tid},
+		{Text: "@bot deploy api web", UserID: 1, ThreadID: tid},
+		{Text: "@bot deploy api", UserID: 2, ThreadID: tid},
+		{Text: "/unknown", UserID: 2, ThreadID: tid},
+		{Text: "/fail", UserID: 2, ThreadID: tid},
+		{Text: "not a command", UserID: 2, ThreadID: tid},
+		{Text: "/status", UserID: 2, ConversationID: conv},
+	} {
+		if err := b.HandleMessage(ctx, m); err != nil {
+			t.Fatalf("HandleMessage(%+v): %v", m, err)
+		}
+	}
+	var got []string
+	for _, c := range srv.Comments(tid) {
+		got = append(got, c.Text)
+	}
+	want := []string{
+		"all good",
+		"deploying api,web",
+		`You are not allowed to run "deploy".`,
+		`Unknown command "unknown".`,
+		`Command "fail" failed.`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("thread comments:\ngot:  %q\nwant: %q", got, want)
+	}
+	if msgs := srv.ConversationMessages(conv); len(msgs) != 1 || msgs[0].Text != "all good" {
+		t.Errorf("unexpected conversation messages: %+v", msgs)
+	}
+}
+
+func TestBot_HandleMessage_ownMessages(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	tid := srv.AddThread(srv.AddChannel(ws, twist.Channel{Name: "General"}), twist.Thread{Title: "Deploys"})
+
+	b := New(srv.Client(), WithName("bot"), WithUserID(42))
+	var calls int
+	b.Handle("status", func(ctx context.Context, r *Request) error {
+		calls++
+		return r.Reply(ctx, "/status is all good")
+	})
+	ctx := context.Background()
+	for _, m := range []Message{
+		{Text: "/status", UserID: 42, ThreadID: tid},
+		{Text: "/unknown", UserID: 42, ThreadID: tid},
+		{Text: "/status", UserID: 2, ThreadID: tid},
+	} {
+		if err := b.HandleMessage(ctx, m); err != nil {
+			t.Fatalf("HandleMessage(%+v): %v", m, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	if cs := srv.Comments(tid); len(cs) != 1 {
+		t.Errorf("got %d replies, want 1: %+v", len(cs), cs)
+	}
+}
+
+func TestAllowUsers(t *testing.T) {
+	var called bool
+	h := AllowUsers(1, 2)(func(context.Context, *Request) error { called = true; return nil })
+	if err := h(context.Background(), &Request{Message: Message{UserID: 3}}); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("got error %v, want ErrNotAllowed", err)
+	}
+	if called {
+		t.Fatal("handler called for not allowed user")
+	}
+	if err := h(context.Background(), &Request{Message: Message{UserID: 2}}); err != nil || !called {
+		t.Fatalf("handler not called for allowed user, error: %v", err)
+	}
+}
+
+func TestBot_Handler(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	tid := srv.AddThread(ch, twist.Thread{Title: "Deploys"})
+
+	b := New(srv.Client())
+	b.Handle("ping", func(ctx context.Context, r *Request) error { return r.Reply(ctx, "pong") })
+
+	vals := url.Values{
+		"verify_token": {"secret"},
+		"thread_id":    {strconv.FormatUint(tid, 10)},
+		"comment_id":   {"56"},
+		"user_id":      {"34"},
+		"content":      {"/ping"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(vals.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	b.Handler("secret").ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	if cs := srv.Comments(tid); len(cs) != 1 || cs[0].Text != "pong" {
+		t.Fatalf("unexpected comments: %+v", cs)
+	}
+}
@@ -0,0 +1,161 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Channel returns a single channel.
+func (c *Client) Channel(ctx context.Context, channelID uint64) (*Channel, error) {
+	if channelID == 0 {
+		return nil, errors.New("invalid channel id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(channelID, 10))
+	var out Channel
+	if err := c.get(ctx, "v3/channels/getone", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NewChannel holds parameters of a channel to create with Client.AddChannel.
+type NewChannel struct {
+	WorkspaceID   uint64 // workspace to create channel in, required
+	Name          string // required
+	Description   string
+	Color         int
+	Public        bool     // channel is visible to all workspace members
+	Members       []uint64 // ids of users to add to channel
+	DefaultGroups []uint64 // ids of groups to notify by default
+}
+
+// AddChannel creates a new channel and returns it.
+func (c *Client) AddChannel(ctx context.Context, ch NewChannel) (*Channel, error) {
+	if ch.WorkspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	if ch.Name == "" {
+		return nil, errors.New("empty channel name")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(ch.WorkspaceID, 10))
+	vals.Add("name", ch.Name)
+	if ch.Description != "" {
+		vals.Add("description", ch.Description)
+	}
+	vals.Add("color", strconv.Itoa(ch.Color))
+	vals.Add("public", strconv.FormatBool(ch.Public))
+	if err := addJSON(vals, "user_ids", ch.Members); err != nil {
+		return nil, err
+	}
+	if err := addJSON(vals, "default_groups", ch.DefaultGroups); err != nil {
+		return nil, err
+	}
+	var out Channel
+	if err := c.post(ctx, "v3/channels/add", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChannelUpdate holds changes to apply to an existing channel with
+// Client.UpdateChannel. Fields with zero values are left unchanged.
+type ChannelUpdate struct {
+	Id            uint64 // channel id, required
+	Name          string
+	Description   string
+	Color         *int
+	Public        *bool
+	DefaultGroups []uint64
+}
+
+// UpdateChannel updates an existing channel and returns its new version.
+func (c *Client) UpdateChannel(ctx context.Context, u ChannelUpdate) (*Channel, error) {
+	if u.Id == 0 {
+		return nil, errors.New("invalid channel id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(u.Id, 10))
+	if u.Name != "" {
+		vals.Add("name", u.Name)
+	}
+	if u.Description != "" {
+		vals.Add("description", u.Description)
+	}
+	if u.Color != nil {
+		vals.Add("color", strconv.Itoa(*u.Color))
+	}
+	if u.Public != nil {
+		vals.Add("public", strconv.FormatBool(*u.Public))
+	}
+	if err := addJSON(vals, "default_groups", u.DefaultGroups); err != nil {
+		return nil, err
+	}
+	var out Channel
+	if err := c.post(ctx, "v3/channels/update", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveChannel permanently removes a channel with all its threads.
+func (c *Client) RemoveChannel(ctx context.Context, channelID uint64) error {
+	return c.postID(ctx, "v3/channels/remove", "channel", channelID)
+}
+
+// ArchiveChannel archives a channel.
+func (c *Client) ArchiveChannel(ctx context.Context, channelID uint64) error {
+	return c.postID(ctx, "v3/channels/archive", "channel", channelID)
+}
+
+// UnarchiveChannel restores a previously archived channel.
+func (c *Client) UnarchiveChannel(ctx context.Context, channelID uint64) error {
+	return c.postID(ctx, "v3/channels/unarchive", "channel", channelID)
+}
+
+// JoinChannel adds the user that owns Client's token to a channel.
+func (c *Client) JoinChannel(ctx context.Context, channelID uint64) error {
+	return c.postID(ctx, "v3/channels/join", "channel", channelID)
+}
+
+// LeaveChannel removes the user that owns Client's token from a channel.
+func (c *Client) LeaveChannel(ctx context.Context, channelID uint64) error {
+	return c.postID(ctx, "v3/channels/leave", "channel", channelID)
+}
+
+// ChannelMembers returns ids of channel members.
+func (c *Client) ChannelMembers(ctx context.Context, channelID uint64) ([]uint64, error) {
+	ch, err := c.Channel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return ch.Members, nil
+}
+
+// AddChannelMembers adds users with given ids to a channel.
+func (c *Client) AddChannelMembers(ctx context.Context, channelID uint64, userIDs ...uint64) error {
+	return c.channelMembersAction(ctx, "v3/channels/add_users", channelID, userIDs)
+}
+
+// RemoveChannelMembers removes users with given ids from a channel.
+func (c *Client) RemoveChannelMembers(ctx context.Context, channelID uint64, userIDs ...uint64) error {
+	return c.channelMembersAction(ctx, "v3/channels/remove_users", channelID, userIDs)
+}
+
+func (c *Client) channelMembersAction(ctx context.Context, endpoint string, channelID uint64, userIDs []uint64) error {
+	if channelID == 0 {
+		return errors.New("invalid channel id")
+	}
+	if len(userIDs) == 0 {
+		return errors.New("empty list of user ids")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(channelID, 10))
+	if err := addJSON(vals, "user_ids", userIDs); err != nil {
+		return err
+	}
+	return c.post(ctx, endpoint, vals, nil)
+}
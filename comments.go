@@ -0,0 +1,107 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// NewComment holds parameters of a comment to post with Client.AddComment.
+type NewComment struct {
+	ThreadID    uint64   // thread to post comment to, required
+	Content     string   // required
+	Recipients  []uint64 // ids of users to notify
+	Groups      []uint64 // ids of groups to notify
+	Attachments []Attachment
+
+	// TempID is an optional client-side id of a comment, that clients use
+	// to match comment they post with its version received from the server.
+	TempID int64
+
+	// SendAsIntegration makes comment appear as posted by the integration
+	// that owns the token, instead of the user.
+	SendAsIntegration bool
+}
+
+// AddComment posts a new comment to a thread and returns it. Returned comment
+// OrderIndex tells its position in the thread, see CommentsPaginator.
+func (c *Client) AddComment(ctx context.Context, nc NewComment) (*Comment, error) {
+	if nc.ThreadID == 0 {
+		return nil, errors.New("invalid thread id")
+	}
+	if nc.Content == "" {
+		return nil, errors.New("empty comment content")
+	}
+	vals := make(url.Values)
+	vals.Add("thread_id", strconv.FormatUint(nc.ThreadID, 10))
+	vals.Add("content", nc.Content)
+	if err := addJSON(vals, "recipients", nc.Recipients); err != nil {
+		return nil, err
+	}
+	if err := addJSON(vals, "groups", nc.Groups); err != nil {
+		return nil, err
+	}
+	if err := addJSON(vals, "attachments", nc.Attachments); err != nil {
+		return nil, err
+	}
+	if nc.TempID != 0 {
+		vals.Add("temp_id", strconv.FormatInt(nc.TempID, 10))
+	}
+	if nc.SendAsIntegration {
+		vals.Add("send_as_integration", "true")
+	}
+	var out Comment
+	if err := c.post(ctx, "v3/comments/add", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CommentUpdate holds changes to apply to an existing comment with
+// Client.UpdateComment. Fields with zero values are left unchanged.
+type CommentUpdate struct {
+	Id          uint64 // comment id, required
+	Content     string
+	Attachments []Attachment
+}
+
+// UpdateComment updates an existing comment and returns its new version.
+func (c *Client) UpdateComment(ctx context.Context, u CommentUpdate) (*Comment, error) {
+	if u.Id == 0 {
+		return nil, errors.New("invalid comment id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(u.Id, 10))
+	if u.Content != "" {
+		vals.Add("content", u.Content)
+	}
+	if err := addJSON(vals, "attachments", u.Attachments); err != nil {
+		return nil, err
+	}
+	var out Comment
+	if err := c.post(ctx, "v3/comments/update", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveComment permanently removes a comment.
+func (c *Client) RemoveComment(ctx context.Context, commentID uint64) error {
+	return c.postID(ctx, "v3/comments/remove", "comment", commentID)
+}
+
+// Comment returns a single comment. Use [CommentsPaginator] to get all
+// comments of a thread.
+func (c *Client) Comment(ctx context.Context, commentID uint64) (*Comment, error) {
+	if commentID == 0 {
+		return nil, errors.New("invalid comment id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(commentID, 10))
+	var out Comment
+	if err := c.get(ctx, "v3/comments/getone", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,282 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Conversation is a Twist conversation: a private chat between two or more
+// workspace users. Conversation contains messages.
+//
+// See https://developer.twist.com/v3/#conversations for details.
+type Conversation struct {
+	Id           uint64   `json:"id"`
+	WorkspaceID  uint64   `json:"workspace_id"`
+	Title        string   `json:"title"`
+	UserIDs      []uint64 `json:"user_ids"`
+	Creator      uint64   `json:"creator"`
+	TsCreated    uint64   `json:"created_ts"`
+	TsActive     uint64   `json:"last_active_ts"`
+	LastObjIndex int      `json:"last_obj_index"`
+	MessageCount int      `json:"message_count"`
+	Archived     bool     `json:"archived"`
+}
+
+// ActiveAt is a convenience method to convert TsActive field to time.
+func (c *Conversation) ActiveAt() time.Time { return time.Unix(int64(c.TsActive), 0) }
+
+// ConversationMessage is a message posted to a conversation.
+//
+// See https://developer.twist.com/v3/#conversation-messages for details.
+type ConversationMessage struct {
+	Id             uint64       `json:"id"`
+	ConversationID uint64       `json:"conversation_id"`
+	Text           string       `json:"content"`
+	Creator        uint64       `json:"creator"`
+	CreatorName    string       `json:"creator_name"`
+	OrderIndex     int          `json:"obj_index"`
+	TsPosted       uint64       `json:"posted_ts"`
+	Reactions      Reactions    `json:"reactions"`
+	Attachments    []Attachment `json:"attachments"`
+}
+
+// PostedAt is a convenience method to convert TsPosted field to time.
+func (m *ConversationMessage) PostedAt() time.Time { return time.Unix(int64(m.TsPosted), 0) }
+
+// Conversations returns all the conversations of a user in a given workspace.
+func (c *Client) Conversations(ctx context.Context, workspaceID uint64) ([]Conversation, error) {
+	if workspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(workspaceID, 10))
+	var out []Conversation
+	if err := c.get(ctx, "v3/conversations/get", vals, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Conversation returns a single conversation. Use
+// [ConversationMessagesPaginator] to get its messages.
+func (c *Client) Conversation(ctx context.Context, conversationID uint64) (*Conversation, error) {
+	if conversationID == 0 {
+		return nil, errors.New("invalid conversation id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(conversationID, 10))
+	var out Conversation
+	if err := c.get(ctx, "v3/conversations/getone", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NewConversationMessage holds parameters of a message to send with
+// Client.AddConversationMessage.
+type NewConversationMessage struct {
+	ConversationID uint64 // conversation to send message to, required
+	Content        string // required
+	Attachments    []Attachment
+}
+
+// AddConversationMessage sends a new message to a conversation and returns it.
+func (c *Client) AddConversationMessage(ctx context.Context, m NewConversationMessage) (*ConversationMessage, error) {
+	if m.ConversationID == 0 {
+		return nil, errors.New("invalid conversation id")
+	}
+	if m.Content == "" {
+		return nil, errors.New("empty message content")
+	}
+	vals := make(url.Values)
+	vals.Add("conversation_id", strconv.FormatUint(m.ConversationID, 10))
+	vals.Add("content", m.Content)
+	if err := addJSON(vals, "attachments", m.Attachments); err != nil {
+		return nil, err
+	}
+	var out ConversationMessage
+	if err := c.post(ctx, "v3/conversation_messages/add", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ConversationMessageUpdate holds changes to apply to an existing message
+// with Client.UpdateConversationMessage. Fields with zero values are left
+// unchanged.
+type ConversationMessageUpdate struct {
+	Id          uint64 // message id, required
+	Content     string
+	Attachments []Attachment
+}
+
+// UpdateConversationMessage updates an existing conversation message and
+// returns its new version.
+func (c *Client) UpdateConversationMessage(ctx context.Context, u ConversationMessageUpdate) (*ConversationMessage, error) {
+	if u.Id == 0 {
+		return nil, errors.New("invalid message id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(u.Id, 10))
+	if u.Content != "" {
+		vals.Add("content", u.Content)
+	}
+	if err := addJSON(vals, "attachments", u.Attachments); err != nil {
+		return nil, err
+	}
+	var out ConversationMessage
+	if err := c.post(ctx, "v3/conversation_messages/update", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveConversationMessage permanently removes a conversation message.
+func (c *Client) RemoveConversationMessage(ctx context.Context, messageID uint64) error {
+	return c.postID(ctx, "v3/conversation_messages/remove", "message", messageID)
+}
+
+// ConversationMessagesPaginator returns ConversationMessagesPaginator that
+// fetches all messages of a conversation.
+func (c *Client) ConversationMessagesPaginator(conversationID uint64) *ConversationMessagesPaginator {
+	return &ConversationMessagesPaginator{c: c, conversationID: conversationID}
+}
+
+// NewConversationMessagesPaginator returns ConversationMessagesPaginator that
+// fetches only conversation messages that were posted since given time.
+//
+// Only use it to update conversation messages you already have on a
+// best-effort basis. Twist API logic is racy and may miss some messages that
+// were posted between per-page API calls. If you need to fetch all messages
+// of a conversation, use ConversationMessagesPaginator method instead.
+func (c *Client) NewConversationMessagesPaginator(conversationID uint64, since time.Time) *ConversationMessagesPaginator {
+	var ts uint64
+	if t := since.Unix(); t > 0 {
+		ts = uint64(t)
+	}
+	return &ConversationMessagesPaginator{c: c, conversationID: conversationID, nextSinceTs: ts}
+}
+
+// ConversationMessagesPaginator fetches messages of a conversation.
+//
+// Typical usage:
+//
+//	p := client.ConversationMessagesPaginator(5678) // get messages for conversation with id=5678
+//	for p.Next() {
+//		messages, err := p.Page(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		doSomethingWithMessages(messages)
+//	}
+type ConversationMessagesPaginator struct {
+	c              *Client
+	conversationID uint64
+	nextIndex      int
+	done           bool
+
+	// only used when fetching new messages
+	nextSinceTs uint64
+}
+
+// Next reports whether there's another page to load. It only returns false
+// once all messages are fetched with the Page method.
+func (mp *ConversationMessagesPaginator) Next() bool { return !mp.done }
+
+// Page returns next portion of conversation messages.
+func (mp *ConversationMessagesPaginator) Page(ctx context.Context) ([]ConversationMessage, error) {
+	if mp.done {
+		return nil, errors.New("all pages already read")
+	}
+	var messages []ConversationMessage
+	var err error
+	if mp.nextSinceTs != 0 {
+		messages, err = mp.c.getNewConversationMessagesPage(ctx, mp.conversationID, mp.nextSinceTs)
+	} else {
+		messages, err = mp.c.getConversationMessagesPage(ctx, mp.conversationID, mp.nextIndex)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mp.nextSinceTs != 0 && len(messages) != 0 {
+		var maxTs uint64
+		for _, m := range messages {
+			if m.TsPosted > maxTs {
+				maxTs = m.TsPosted
+			}
+		}
+		if maxTs != 0 {
+			// +1 to avoid duplicates on page boundaries, API uses closed
+			// interval
+			mp.nextSinceTs = maxTs + 1
+		}
+	}
+	mp.done = len(messages) < maxMessagesPerPage
+	if l := len(messages); l != 0 {
+		mp.nextIndex = messages[l-1].OrderIndex + 1
+	}
+	return messages, nil
+}
+
+// getNewConversationMessagesPage returns chunk of messages using loose window
+// based on newer_than_ts API argument. Results are not ordered, may contain
+// duplicates, and may miss some messages that were posted concurrently with
+// this API call.
+func (c *Client) getNewConversationMessagesPage(ctx context.Context, conversationID, sinceTimestamp uint64) ([]ConversationMessage, error) {
+	if conversationID == 0 {
+		return nil, errors.New("invalid conversation ID")
+	}
+	vals := make(url.Values)
+	vals.Add("conversation_id", strconv.FormatUint(conversationID, 10))
+	vals.Add("limit", strconv.Itoa(maxMessagesPerPage))
+	vals.Add("newer_than_ts", strconv.FormatUint(sinceTimestamp, 10))
+	var out []ConversationMessage
+	if err := c.get(ctx, "v3/conversation_messages/get", vals, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// getConversationMessagesPage returns chunk of messages using precise window
+// based on {from,to}_obj_index API arguments, suitable to reliably get all
+// conversation messages. Messages returned are ordered by OrderIndex
+// increasing without gaps.
+func (c *Client) getConversationMessagesPage(ctx context.Context, conversationID uint64, fromIndex int) ([]ConversationMessage, error) {
+	if fromIndex < 0 {
+		panic("fromIndex must be non-negative")
+	}
+	if conversationID == 0 {
+		return nil, errors.New("invalid conversation ID")
+	}
+	vals := make(url.Values)
+	vals.Add("conversation_id", strconv.FormatUint(conversationID, 10))
+	vals.Add("limit", strconv.Itoa(maxMessagesPerPage))
+	vals.Add("from_obj_index", strconv.Itoa(fromIndex))
+	vals.Add("to_obj_index", strconv.Itoa(fromIndex+maxMessagesPerPage-1))
+	var out []ConversationMessage
+	if err := c.get(ctx, "v3/conversation_messages/get", vals, &out); err != nil {
+		return nil, err
+	}
+	if !sort.SliceIsSorted(out, func(i, j int) bool { return out[i].OrderIndex < out[j].OrderIndex }) {
+		return nil, errors.New("API returned messages that are not properly sorted by obj_index")
+	}
+	var offset int
+	for i, m := range out { // sanity check
+		if i == 0 {
+			offset = m.OrderIndex
+			continue
+		}
+		if want := offset + i; m.OrderIndex != want {
+			return nil, fmt.Errorf("ordering issue in messages: order index is %d, expected %d",
+				m.OrderIndex, want)
+		}
+	}
+	return out, nil
+}
+
+const maxMessagesPerPage = 500
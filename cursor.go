@@ -0,0 +1,130 @@
+package twist
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// MarshalText implements encoding.TextMarshaler. It encodes paginator
+// position, so it can be checkpointed and later resumed with
+// Client.ResumeThreadsPaginator, possibly in another process.
+//
+// Position advances a whole page at a time, once Page returns. Checkpoint
+// between Page calls: if All is stopped in the middle of a page, paginator
+// resumed from its cursor starts with the next page, skipping the rest of
+// the current one.
+func (cp *ThreadsPaginator) MarshalText() ([]byte, error) {
+	return cursor{kind: "threads", id: cp.channelID, pos: cp.afterID, since: cp.nextSinceTs, done: cp.done, filter: cp.filter}.marshal(), nil
+}
+
+// ResumeThreadsPaginator returns ThreadsPaginator that continues from the
+// position encoded in text, as returned by ThreadsPaginator.MarshalText. It
+// fails if text was produced by a paginator for a different channel.
+func (c *Client) ResumeThreadsPaginator(channelID uint64, text []byte) (*ThreadsPaginator, error) {
+	cur, err := parseCursor(text, "threads", channelID)
+	if err != nil {
+		return nil, err
+	}
+	return &ThreadsPaginator{c: c, channelID: channelID, afterID: cur.pos, nextSinceTs: cur.since, done: cur.done, filter: cur.filter}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler. It encodes paginator
+// position, so it can be checkpointed and later resumed with
+// Client.ResumeCommentsPaginator, possibly in another process.
+//
+// Position advances a whole page at a time, see ThreadsPaginator.MarshalText.
+func (tp *CommentsPaginator) MarshalText() ([]byte, error) {
+	return cursor{kind: "comments", id: tp.threadID, pos: uint64(tp.nextIndex), since: tp.nextSinceTs, done: tp.done}.marshal(), nil
+}
+
+// ResumeCommentsPaginator returns CommentsPaginator that continues from the
+// position encoded in text, as returned by CommentsPaginator.MarshalText. It
+// fails if text was produced by a paginator for a different thread.
+func (c *Client) ResumeCommentsPaginator(threadID uint64, text []byte) (*CommentsPaginator, error) {
+	cur, err := parseCursor(text, "comments", threadID)
+	if err != nil {
+		return nil, err
+	}
+	return &CommentsPaginator{c: c, threadID: threadID, nextIndex: int(cur.pos), nextSinceTs: cur.since, done: cur.done}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler. It encodes paginator
+// position, so it can be checkpointed and later resumed with
+// Client.ResumeConversationMessagesPaginator, possibly in another process.
+//
+// Position advances a whole page at a time, see ThreadsPaginator.MarshalText.
+func (mp *ConversationMessagesPaginator) MarshalText() ([]byte, error) {
+	return cursor{kind: "messages", id: mp.conversationID, pos: uint64(mp.nextIndex), since: mp.nextSinceTs, done: mp.done}.marshal(), nil
+}
+
+// ResumeConversationMessagesPaginator returns ConversationMessagesPaginator
+// that continues from the position encoded in text, as returned by
+// ConversationMessagesPaginator.MarshalText. It fails if text was produced by
+// a paginator for a different conversation.
+func (c *Client) ResumeConversationMessagesPaginator(conversationID uint64, text []byte) (*ConversationMessagesPaginator, error) {
+	cur, err := parseCursor(text, "messages", conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return &ConversationMessagesPaginator{c: c, conversationID: conversationID, nextIndex: int(cur.pos), nextSinceTs: cur.since, done: cur.done}, nil
+}
+
+// cursor is a serializable paginator position.
+type cursor struct {
+	kind  string // type of paginated objects
+	id    uint64 // id of the object which children are paginated
+	pos   uint64 // position for precise pagination: after_id or obj_index
+	since uint64 // position for loose pagination: newer_than_ts
+	done  bool
+
+	filter string // optional filter_by API argument
+}
+
+func (c cursor) marshal() []byte {
+	vals := make(url.Values)
+	vals.Set("kind", c.kind)
+	vals.Set("id", strconv.FormatUint(c.id, 10))
+	vals.Set("pos", strconv.FormatUint(c.pos, 10))
+	vals.Set("since", strconv.FormatUint(c.since, 10))
+	vals.Set("done", strconv.FormatBool(c.done))
+	if c.filter != "" {
+		vals.Set("filter", c.filter)
+	}
+	return []byte(vals.Encode())
+}
+
+// parseCursor decodes cursor, checking that it's of a given kind and
+// belongs to an object with a given id.
+func parseCursor(text []byte, kind string, id uint64) (cursor, error) {
+	if id == 0 {
+		return cursor{}, errors.New("invalid id")
+	}
+	vals, err := url.ParseQuery(string(text))
+	if err != nil {
+		return cursor{}, fmt.Errorf("malformed cursor: %w", err)
+	}
+	if k := vals.Get("kind"); k != kind {
+		return cursor{}, fmt.Errorf("cursor is for %q, not %q", k, kind)
+	}
+	c := cursor{kind: kind, filter: vals.Get("filter")}
+	for _, f := range [...]struct {
+		name string
+		dst  *uint64
+	}{{"id", &c.id}, {"pos", &c.pos}, {"since", &c.since}} {
+		if *f.dst, err = strconv.ParseUint(vals.Get(f.name), 10, 64); err != nil {
+			return cursor{}, fmt.Errorf("malformed cursor %s: %w", f.name, err)
+		}
+	}
+	if c.done, err = strconv.ParseBool(vals.Get("done")); err != nil {
+		return cursor{}, fmt.Errorf("malformed cursor done flag: %w", err)
+	}
+	if c.id != id {
+		return cursor{}, fmt.Errorf("cursor belongs to id %d, not %d", c.id, id)
+	}
+	if c.pos > 1<<31-1 && kind != "threads" {
+		return cursor{}, errors.New("malformed cursor: position out of range")
+	}
+	return c, nil
+}
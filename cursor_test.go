@@ -0,0 +1,90 @@
+package twist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResumeCommentsPaginator(t *testing.T) {
+	c := New("")
+	p := c.CommentsPaginator(42)
+	p.nextIndex, p.nextSinceTs = 500, 1700000000
+	text, err := p.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := c.ResumeCommentsPaginator(42, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2.threadID != p.threadID || p2.nextIndex != p.nextIndex || p2.nextSinceTs != p.nextSinceTs || p2.done != p.done {
+		t.Fatalf("resumed paginator %+v differs from original %+v", p2, p)
+	}
+	if _, err := c.ResumeCommentsPaginator(43, text); err == nil {
+		t.Fatal("resuming paginator for another thread succeeded")
+	}
+	if _, err := c.ResumeThreadsPaginator(42, text); err == nil {
+		t.Fatal("resuming threads paginator from comments cursor succeeded")
+	}
+}
+
+func TestResumeThreadsPaginator(t *testing.T) {
+	c := New("")
+	p := c.ThreadsPaginator(42)
+	p.afterID, p.done, p.filter = 123456, true, "is_pinned"
+	text, err := p.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := c.ResumeThreadsPaginator(42, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2.channelID != p.channelID || p2.afterID != p.afterID || p2.nextSinceTs != p.nextSinceTs ||
+		p2.done != p.done || p2.filter != p.filter {
+		t.Fatalf("resumed paginator %+v differs from original %+v", p2, p)
+	}
+	if _, err := c.ResumeThreadsPaginator(43, text); err == nil {
+		t.Fatal("resuming paginator for another channel succeeded")
+	}
+	if _, err := c.ResumeConversationMessagesPaginator(42, text); err == nil {
+		t.Fatal("resuming messages paginator from threads cursor succeeded")
+	}
+
+	p = c.NewThreadsPaginator(42, time.Unix(1700000000, 0))
+	if text, err = p.MarshalText(); err != nil {
+		t.Fatal(err)
+	}
+	if p2, err = c.ResumeThreadsPaginator(42, text); err != nil {
+		t.Fatal(err)
+	}
+	if p2.nextSinceTs != 1700000000 || p2.filter != "" {
+		t.Fatalf("resumed paginator %+v differs from original %+v", p2, p)
+	}
+}
+
+func TestResumeConversationMessagesPaginator(t *testing.T) {
+	c := New("")
+	p := c.ConversationMessagesPaginator(42)
+	p.nextIndex = 1000
+	text, err := p.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := c.ResumeConversationMessagesPaginator(42, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2.conversationID != p.conversationID || p2.nextIndex != p.nextIndex || p2.nextSinceTs != p.nextSinceTs || p2.done != p.done {
+		t.Fatalf("resumed paginator %+v differs from original %+v", p2, p)
+	}
+	if _, err := c.ResumeConversationMessagesPaginator(43, text); err == nil {
+		t.Fatal("resuming paginator for another conversation succeeded")
+	}
+	if _, err := c.ResumeCommentsPaginator(42, text); err == nil {
+		t.Fatal("resuming comments paginator from messages cursor succeeded")
+	}
+	if _, err := c.ResumeConversationMessagesPaginator(42, []byte("kind=messages&id=42&pos=x&since=0&done=false")); err == nil {
+		t.Fatal("resuming paginator from malformed cursor succeeded")
+	}
+}
@@ -5,13 +5,12 @@ import (
 	"cmp"
 	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -30,12 +29,14 @@ func main() {
 	if v, _ := strconv.ParseBool(os.Getenv("DUMP_TWIST_THREAD_CACHE")); v && !*cache {
 		*cache = v
 	}
-	if err := run(context.Background(), *cache, flag.Arg(0)); err != nil {
+	if err := run(context.Background(), os.Stdout, *cache, flag.Arg(0)); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(ctx context.Context, cache bool, threadURL string) error {
+// run writes thread or conversation at threadURL to w. Options are passed to
+// twist.New.
+func run(ctx context.Context, w io.Writer, cache bool, threadURL string, opts ...twist.Option) error {
 	pruneCache()
 	if threadURL == "" {
 		return errors.New("want Twist thread url as the first argument")
@@ -46,7 +47,7 @@ func run(ctx context.Context, cache bool, threadURL string) error {
 	}
 	if strings.Contains(threadURL, "/msg/") {
 		// TODO: consolidate logic?
-		return dumpChat(ctx, cache, token, threadURL)
+		return dumpChat(ctx, w, cache, token, threadURL, opts...)
 	}
 	ids, err := tidFromURL(threadURL)
 	if err != nil {
@@ -54,18 +55,18 @@ func run(ctx context.Context, cache bool, threadURL string) error {
 	}
 	if cache {
 		if b := readCache(threadURL); len(b) != 0 {
-			_, err = os.Stdout.Write(b)
+			_, err = w.Write(b)
 			return err
 		}
 	}
-	client := twist.New(token)
+	client := twist.New(token, opts...)
 	users, err := client.Users(ctx, ids.workspace)
 	if err != nil {
 		return fmt.Errorf("getting workspace users: %w", err)
 	}
-	uidToName := make(map[uint64]string)
+	usersByID := make(map[uint64]twist.User)
 	for _, u := range users {
-		uidToName[u.Id] = cmp.Or(u.ShortName, u.Name)
+		usersByID[u.Id] = u
 	}
 	thread, err := client.Thread(ctx, ids.thread)
 	if err != nil {
@@ -73,32 +74,53 @@ func run(ctx context.Context, cache bool, threadURL string) error {
 	}
 	var buf bytes.Buffer
 	buf.WriteString("<post>\n")
-	fmt.Fprintf(&buf, "<author>%s</author>", cmp.Or(uidToName[thread.Creator], "UNKNOWN USER"))
-	fmt.Fprintf(&buf, "<date>%s</date>\n", thread.PostedAt().Format("Monday, 02 Jan 2006"))
+	fmt.Fprintf(&buf, "<author>%s</author>", authorName(usersByID, thread.Creator))
+	fmt.Fprintf(&buf, "<date>%s</date>\n", authorTime(usersByID, thread.Creator, thread.PostedAt()).Format("Monday, 02 Jan 2006"))
 	fmt.Fprintf(&buf, "# %s\n\n", thread.Title)
 	fmt.Fprintln(&buf, clearMentions(thread.Text))
 	buf.WriteString("</post>\n")
-	p := client.CommentsPaginator(ids.thread)
-	for p.Next() {
-		comments, err := p.Page(ctx)
+	for c, err := range client.AllComments(ctx, ids.thread) {
 		if err != nil {
 			return fmt.Errorf("reading thread comments: %w", err)
 		}
-		for _, c := range comments {
-			buf.WriteString("<comment>\n")
-			fmt.Fprintf(&buf, "<author>%s</author>", cmp.Or(uidToName[c.Creator], "UNKNOWN USER"))
-			fmt.Fprintf(&buf, "<date>%s</date>\n", c.PostedAt().Format("Monday, 02 Jan 2006"))
-			fmt.Fprintln(&buf, clearMentions(c.Text))
-			buf.WriteString("</comment>\n")
-		}
+		buf.WriteString("<comment>\n")
+		fmt.Fprintf(&buf, "<author>%s</author>", authorName(usersByID, c.Creator))
+		fmt.Fprintf(&buf, "<date>%s</date>\n", authorTime(usersByID, c.Creator, c.PostedAt()).Format("Monday, 02 Jan 2006"))
+		fmt.Fprintln(&buf, clearMentions(c.Text))
+		buf.WriteString("</comment>\n")
 	}
 	if cache {
 		writeCache(threadURL, buf.Bytes())
 	}
-	_, err = os.Stdout.Write(buf.Bytes())
+	_, err = w.Write(buf.Bytes())
 	return err
 }
 
+// authorName returns display name of a user with a given id, marking bots
+// and users removed from the workspace.
+func authorName(users map[uint64]twist.User, uid uint64) string {
+	u, ok := users[uid]
+	if !ok {
+		return "UNKNOWN USER"
+	}
+	name := cmp.Or(u.ShortName, u.Name, "UNKNOWN USER")
+	switch {
+	case u.Bot:
+		name += " (bot)"
+	case u.Removed:
+		name += " (removed)"
+	}
+	return name
+}
+
+// authorTime returns t in time zone of a user with a given id, if known.
+func authorTime(users map[uint64]twist.User, uid uint64, t time.Time) time.Time {
+	if u, ok := users[uid]; ok {
+		return t.In(u.Location())
+	}
+	return t
+}
+
 var twistThreadURL = regexp.MustCompile(`^https://twist\.com/a/(\d+)/ch/(\d+)/t/(\d+)/?$`)
 
 func tidFromURL(url string) (*tid, error) {
@@ -124,7 +146,8 @@ type tid struct {
 	workspace, channel, thread uint64
 }
 
-var mentionRe = regexp.MustCompile(`\[(?<name>[^\]]+)\]\(twist-mention://\d+\)`)
+// mentionRe matches both user and group mentions
+var mentionRe = regexp.MustCompile(`\[(?<name>[^\]]+)\]\(twist-(?:group-)?mention://\d+\)`)
 
 func clearMentions(text string) string { return mentionRe.ReplaceAllString(text, "${name}") }
 
@@ -191,7 +214,7 @@ func init() {
 
 var twistChatURL = regexp.MustCompile(`^\Qhttps://twist.com/a/\E(?:\d+)/msg/(\d+)/$`)
 
-func dumpChat(ctx context.Context, cache bool, token, url string) error {
+func dumpChat(ctx context.Context, w io.Writer, cache bool, token, url string, opts ...twist.Option) error {
 	m := twistChatURL.FindStringSubmatch(url)
 	if m == nil {
 		return fmt.Errorf("%q does not match %v", url, twistChatURL)
@@ -199,51 +222,29 @@ func dumpChat(ctx context.Context, cache bool, token, url string) error {
 
 	if cache {
 		if b := readCache(url); len(b) != 0 {
-			_, err := os.Stdout.Write(b)
+			_, err := w.Write(b)
 			return err
 		}
 	}
 
-	const limit = 500
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v3/conversation_messages/get?conversation_id="+m[1]+"&limit="+strconv.Itoa(limit), nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	resp, err := http.DefaultClient.Do(req)
+	conversationID, err := strconv.ParseUint(m[1], 10, 64)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %s", resp.Status)
-	}
-	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
-		return fmt.Errorf("unexpected content-type: %q", ct)
-	}
-
-	dec := json.NewDecoder(resp.Body)
-	var out []struct {
-		Text      string `json:"content"`
-		Author    string `json:"creator_name"`
-		Timestamp int64  `json:"posted_ts"`
-	}
-	if err := dec.Decode(&out); err != nil {
-		return err
-	}
+	client := twist.New(token, opts...)
 	var buf bytes.Buffer
-	if len(out) == limit {
-		buf.WriteString("(earlier messages not shown)\n\n")
-	}
-	for _, msg := range out {
-		fmt.Fprintf(&buf, "<msg><author>%s</author>", msg.Author)
-		fmt.Fprintf(&buf, "<date>%s</date>\n", time.Unix(msg.Timestamp, 0).Format("Monday, 02 Jan 2006 15:04"))
+	for msg, err := range client.AllConversationMessages(ctx, conversationID) {
+		if err != nil {
+			return fmt.Errorf("reading conversation messages: %w", err)
+		}
+		fmt.Fprintf(&buf, "<msg><author>%s</author>", msg.CreatorName)
+		fmt.Fprintf(&buf, "<date>%s</date>\n", msg.PostedAt().Format("Monday, 02 Jan 2006 15:04"))
 		fmt.Fprintln(&buf, clearMentions(msg.Text))
 		buf.WriteString("</msg>\n")
 	}
 	if cache {
 		writeCache(url, buf.Bytes())
 	}
-	_, err = os.Stdout.Write(buf.Bytes())
+	_, err = w.Write(buf.Bytes())
 	return err
 }
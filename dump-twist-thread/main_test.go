@@ -1,14 +1,115 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"flag"
+	"net/http"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/twisttest"
 )
 
+var record = flag.Bool("record", false, "record Twist API interactions to testdata")
+
+func TestRun(t *testing.T) {
+	// recording needs TWIST_TOKEN of a user who can access thread and
+	// conversation below, run requires it to be set even when replaying
+	token := os.Getenv("TWIST_TOKEN")
+	if *record && token == "" {
+		t.Fatal("TWIST_TOKEN must be set to record")
+	}
+	t.Setenv("TWIST_TOKEN", cmp.Or(token, "token"))
+	// conversation messages are printed in local time
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.UTC
+	for _, tc := range []struct {
+		name, url, want string
+	}{
+		{
+			name: "thread",
+			url:  "https://twist.com/a/71234/ch/280531/t/4817262/",
+			want: `<post>
+<author>Maria</author><date>Tuesday, 12 Mar 2024</date>
+# Database failover drill
+
+We'll run the failover drill on Thursday. Kenji please prepare the runbook.
+</post>
+<comment>
+<author>Kenji</author><date>Wednesday, 13 Mar 2024</date>
+Runbook is ready, see the wiki page.
+</comment>
+<comment>
+<author>GitHub (bot)</author><date>Wednesday, 13 Mar 2024</date>
+PR #1432 merged: failover script timeout raised to 60s
+</comment>
+<comment>
+<author>Alex (removed)</author><date>Thursday, 14 Mar 2024</date>
+Replica lag was under a second during the drill.
+</comment>
+<comment>
+<author>Maria</author><date>Thursday, 14 Mar 2024</date>
+Thanks @backend, closing this.
+</comment>
+`,
+		},
+		{
+			name: "conversation",
+			url:  "https://twist.com/a/71234/msg/1203344/",
+			want: `<msg><author>Maria Lopez</author><date>Thursday, 14 Mar 2024 12:10</date>
+Do you have a minute to review the drill notes?
+</msg>
+<msg><author>Kenji Sato</author><date>Thursday, 14 Mar 2024 12:14</date>
+Sure, Maria, sending comments in an hour.
+</msg>
+`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := twisttest.RecordOrReplay(t, "testdata/"+tc.name+".json", *record, token)
+			var buf bytes.Buffer
+			err := run(context.Background(), &buf, false, tc.url,
+				twist.WithHTTPClient(&http.Client{Transport: rt}), twist.WithRetryPolicy(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *record {
+				return
+			}
+			if buf.String() != tc.want {
+				t.Fatalf("got output:\n%s\nwant:\n%s", &buf, tc.want)
+			}
+		})
+	}
+}
+
 func Test_clearMentions(t *testing.T) {
-	const text = `Hello [Thomas](twist-mention://123), how are you?`
-	const want = "Hello Thomas, how are you?"
+	const text = `Hello [Thomas](twist-mention://123) and [@backend](twist-group-mention://45), how are you?`
+	const want = "Hello Thomas and @backend, how are you?"
 	got := clearMentions(text)
 	if got != want {
 		t.Fatalf("got %q, want %q", got, want)
 	}
 }
+
+func Test_authorName(t *testing.T) {
+	users := map[uint64]twist.User{
+		1: {Id: 1, Name: "Thomas Anderson", ShortName: "Thomas"},
+		2: {Id: 2, Name: "GitHub", Bot: true},
+		3: {Id: 3, Name: "Cypher", Removed: true},
+	}
+	for uid, want := range map[uint64]string{
+		1: "Thomas",
+		2: "GitHub (bot)",
+		3: "Cypher (removed)",
+		4: "UNKNOWN USER",
+	} {
+		if got := authorName(users, uid); got != want {
+			t.Errorf("authorName(%d): got %q, want %q", uid, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package twist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// APIError is returned when Twist API responds with a non-200 status. Use
+// errors.As to access it, or one of IsNotFound, IsUnauthorized,
+// IsRateLimited helpers to check for common cases.
+type APIError struct {
+	Endpoint   string // API endpoint called, i.e. "v3/threads/getone"
+	StatusCode int    // HTTP status code
+	Status     string // HTTP status, i.e. "404 Not Found"
+	Code       int    // Twist error code, zero if response had none
+	Message    string // Twist error description, empty if response had none
+	Retries    int    // number of retries done before this error was returned
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: unexpected status: %q", e.Endpoint, e.Status)
+	}
+	return fmt.Sprintf("%s: unexpected status: %q: %s (error code %d)",
+		e.Endpoint, e.Status, e.Message, e.Code)
+}
+
+// newAPIError builds APIError from a response with non-200 status. It reads
+// response body to extract Twist-specific error details, if any.
+func newAPIError(endpoint string, resp *http.Response, retries int) *APIError {
+	e := &APIError{
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Retries:    retries,
+	}
+	var body struct {
+		Code    int    `json:"error_code"`
+		Message string `json:"error_string"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err == nil {
+		e.Code, e.Message = body.Code, body.Message
+	}
+	return e
+}
+
+// IsNotFound reports whether err is an *APIError with 404 Not Found status.
+func IsNotFound(err error) bool { return hasStatus(err, http.StatusNotFound) }
+
+// IsUnauthorized reports whether err is an *APIError with either 401
+// Unauthorized or 403 Forbidden status, which Twist uses for invalid or
+// revoked tokens, and for tokens lacking required scopes.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// IsRateLimited reports whether err is an *APIError with 429 Too Many
+// Requests status.
+func IsRateLimited(err error) bool { return hasStatus(err, http.StatusTooManyRequests) }
+
+func hasStatus(err error, code int) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.StatusCode == code
+}
+
+// redactURLError removes query and user info from URL of *url.Error, which
+// http.Client returns for transport failures. Query may carry secrets, like
+// integration install token or signature of attachment download URL, and
+// errors end up in logs.
+func redactURLError(err error) error {
+	ue, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	var redacted string
+	if u, perr := url.Parse(ue.URL); perr == nil {
+		u.User, u.RawQuery, u.ForceQuery, u.Fragment = nil, "", false, ""
+		redacted = u.String()
+	}
+	return &url.Error{Op: ue.Op, URL: redacted, Err: ue.Err}
+}
@@ -0,0 +1,136 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Group is a named set of workspace users, like @backend, that can be
+// notified as a whole.
+//
+// See https://developer.twist.com/v3/#groups for details.
+type Group struct {
+	Id          uint64   `json:"id"`
+	WorkspaceID uint64   `json:"workspace_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Members     []uint64 `json:"user_ids"` // ids of group members
+}
+
+// Groups returns all the groups in a given workspace.
+func (c *Client) Groups(ctx context.Context, workspaceID uint64) ([]Group, error) {
+	if workspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(workspaceID, 10))
+	var out []Group
+	if err := c.get(ctx, "v3/groups/get", vals, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Group returns a single group.
+func (c *Client) Group(ctx context.Context, groupID uint64) (*Group, error) {
+	if groupID == 0 {
+		return nil, errors.New("invalid group id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(groupID, 10))
+	var out Group
+	if err := c.get(ctx, "v3/groups/getone", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NewGroup holds parameters of a group to create with Client.AddGroup.
+type NewGroup struct {
+	WorkspaceID uint64 // workspace to create group in, required
+	Name        string // required
+	Description string
+	Members     []uint64 // ids of users to add to group
+}
+
+// AddGroup creates a new group and returns it.
+func (c *Client) AddGroup(ctx context.Context, g NewGroup) (*Group, error) {
+	if g.WorkspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	if g.Name == "" {
+		return nil, errors.New("empty group name")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(g.WorkspaceID, 10))
+	vals.Add("name", g.Name)
+	if g.Description != "" {
+		vals.Add("description", g.Description)
+	}
+	if err := addJSON(vals, "user_ids", g.Members); err != nil {
+		return nil, err
+	}
+	var out Group
+	if err := c.post(ctx, "v3/groups/add", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GroupUpdate holds changes to apply to an existing group with
+// Client.UpdateGroup. Fields with zero values are left unchanged.
+type GroupUpdate struct {
+	Id          uint64 // group id, required
+	Name        string
+	Description string
+}
+
+// UpdateGroup updates an existing group and returns its new version.
+func (c *Client) UpdateGroup(ctx context.Context, u GroupUpdate) (*Group, error) {
+	if u.Id == 0 {
+		return nil, errors.New("invalid group id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(u.Id, 10))
+	if u.Name != "" {
+		vals.Add("name", u.Name)
+	}
+	if u.Description != "" {
+		vals.Add("description", u.Description)
+	}
+	var out Group
+	if err := c.post(ctx, "v3/groups/update", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveGroup permanently removes a group.
+func (c *Client) RemoveGroup(ctx context.Context, groupID uint64) error {
+	return c.postID(ctx, "v3/groups/remove", "group", groupID)
+}
+
+// AddUserToGroup adds a user to a group.
+func (c *Client) AddUserToGroup(ctx context.Context, groupID, userID uint64) error {
+	return c.groupUserAction(ctx, "v3/groups/add_user", groupID, userID)
+}
+
+// RemoveUserFromGroup removes a user from a group.
+func (c *Client) RemoveUserFromGroup(ctx context.Context, groupID, userID uint64) error {
+	return c.groupUserAction(ctx, "v3/groups/remove_user", groupID, userID)
+}
+
+func (c *Client) groupUserAction(ctx context.Context, endpoint string, groupID, userID uint64) error {
+	if groupID == 0 {
+		return errors.New("invalid group id")
+	}
+	if userID == 0 {
+		return errors.New("invalid user id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(groupID, 10))
+	vals.Add("user_id", strconv.FormatUint(userID, 10))
+	return c.post(ctx, endpoint, vals, nil)
+}
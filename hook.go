@@ -0,0 +1,116 @@
+package twist
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Hook observes API calls made by Client. Its methods are called
+// synchronously from the goroutine making the call, so they should return
+// quickly.
+type Hook interface {
+	// OnRequest is called before each attempt to send request.
+	OnRequest(context.Context, RequestEvent)
+	// OnResponse is called once attempt completes. For successful calls
+	// this happens once response body is fully consumed and closed.
+	OnResponse(context.Context, ResponseEvent)
+	// OnRetry is called before failed attempt is retried.
+	OnRetry(context.Context, RetryEvent)
+}
+
+// WithHook makes Client report its API calls to h. This option can be used
+// multiple times to register more than one hook.
+func WithHook(h Hook) Option {
+	return func(c *Client) {
+		if h != nil {
+			c.hooks = append(c.hooks, h)
+		}
+	}
+}
+
+// RequestEvent describes an attempt to send API request.
+type RequestEvent struct {
+	Endpoint string // API endpoint called, i.e. "v3/threads/getone"
+	Method   string // HTTP method
+	Attempt  int    // number of attempt, starting from 1
+}
+
+// ResponseEvent describes a completed attempt to send API request.
+type ResponseEvent struct {
+	Endpoint   string        // API endpoint called, i.e. "v3/threads/getone"
+	Method     string        // HTTP method
+	Attempt    int           // number of attempt, starting from 1
+	StatusCode int           // HTTP status code, zero if no response was received
+	Latency    time.Duration // time since request was sent until response body was closed
+	BytesRead  int64         // number of response body bytes read
+	Err        error         // non-nil if attempt failed
+}
+
+// NewSlogHook returns Hook that logs API calls to l: requests and responses
+// are logged at debug level, retries and failed responses are logged at
+// warning level.
+func NewSlogHook(l *slog.Logger) Hook { return slogHook{l: l} }
+
+type slogHook struct{ l *slog.Logger }
+
+func (h slogHook) OnRequest(ctx context.Context, e RequestEvent) {
+	h.l.LogAttrs(ctx, slog.LevelDebug, "twist request",
+		slog.String("endpoint", e.Endpoint),
+		slog.String("method", e.Method),
+		slog.Int("attempt", e.Attempt))
+}
+
+func (h slogHook) OnResponse(ctx context.Context, e ResponseEvent) {
+	attrs := []slog.Attr{
+		slog.String("endpoint", e.Endpoint),
+		slog.String("method", e.Method),
+		slog.Int("attempt", e.Attempt),
+		slog.Int("status", e.StatusCode),
+		slog.Duration("latency", e.Latency),
+		slog.Int64("bytes", e.BytesRead),
+	}
+	if e.Err != nil {
+		h.l.LogAttrs(ctx, slog.LevelWarn, "twist response", append(attrs, slog.Any("error", e.Err))...)
+		return
+	}
+	h.l.LogAttrs(ctx, slog.LevelDebug, "twist response", attrs...)
+}
+
+func (h slogHook) OnRetry(ctx context.Context, e RetryEvent) {
+	h.l.LogAttrs(ctx, slog.LevelWarn, "twist retry",
+		slog.String("endpoint", e.Endpoint),
+		slog.Int("attempt", e.Attempt),
+		slog.Duration("delay", e.Delay),
+		slog.Any("error", e.Err))
+}
+
+// retryHookFunc adapts function passed to WithRetryHook to Hook interface.
+type retryHookFunc func(context.Context, RetryEvent)
+
+func (retryHookFunc) OnRequest(context.Context, RequestEvent)      {}
+func (retryHookFunc) OnResponse(context.Context, ResponseEvent)    {}
+func (fn retryHookFunc) OnRetry(ctx context.Context, e RetryEvent) { fn(ctx, e) }
+
+// observedBody wraps response body to count bytes read from it, calling
+// onClose once body is closed.
+type observedBody struct {
+	io.ReadCloser
+	n       int64
+	once    sync.Once
+	onClose func(bytesRead int64)
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.onClose(b.n) })
+	return err
+}
@@ -0,0 +1,132 @@
+package twist_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/twisttest"
+)
+
+type recordingHook struct {
+	mu        sync.Mutex
+	requests  []twist.RequestEvent
+	responses []twist.ResponseEvent
+	retries   []twist.RetryEvent
+}
+
+func (h *recordingHook) OnRequest(_ context.Context, e twist.RequestEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests = append(h.requests, e)
+}
+
+func (h *recordingHook) OnResponse(_ context.Context, e twist.ResponseEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.responses = append(h.responses, e)
+}
+
+func (h *recordingHook) OnRetry(_ context.Context, e twist.RetryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retries = append(h.retries, e)
+}
+
+func TestWithHook(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		h := new(recordingHook)
+		if _, err := srv.Client(twist.WithHook(h)).Workspaces(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(h.requests) != 1 || len(h.responses) != 1 || len(h.retries) != 0 {
+			t.Fatalf("got %d requests, %d responses, %d retries, want 1, 1, 0",
+				len(h.requests), len(h.responses), len(h.retries))
+		}
+		e := h.responses[0]
+		if e.Endpoint != "v3/workspaces/get" || e.Method != "GET" || e.Attempt != 1 || e.StatusCode != 200 {
+			t.Fatalf("unexpected response event: %+v", e)
+		}
+		if e.Err != nil || e.BytesRead == 0 || e.Latency <= 0 {
+			t.Fatalf("unexpected response event: %+v", e)
+		}
+	})
+
+	t.Run("retried 503", func(t *testing.T) {
+		srv.InjectFault(twisttest.Fault{Endpoint: "v3/workspaces/get", Times: 1, Status: 503})
+		h := new(recordingHook)
+		if _, err := srv.Client(twist.WithHook(h)).Workspaces(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(h.requests) != 2 || len(h.responses) != 2 || len(h.retries) != 1 {
+			t.Fatalf("got %d requests, %d responses, %d retries, want 2, 2, 1",
+				len(h.requests), len(h.responses), len(h.retries))
+		}
+		var apiErr *twist.APIError
+		if e := h.responses[0]; e.Attempt != 1 || e.StatusCode != 503 || !errors.As(e.Err, &apiErr) || e.BytesRead == 0 {
+			t.Fatalf("unexpected first response event: %+v", e)
+		}
+		if e := h.responses[1]; e.Attempt != 2 || e.StatusCode != 200 || e.Err != nil {
+			t.Fatalf("unexpected second response event: %+v", e)
+		}
+		if e := h.retries[0]; e.Attempt != 1 || e.Err == nil {
+			t.Fatalf("unexpected retry event: %+v", e)
+		}
+	})
+
+	t.Run("bad Content-Type", func(t *testing.T) {
+		srv.InjectFault(twisttest.Fault{Endpoint: "v3/workspaces/get", Times: 1, BadContentType: true})
+		h := new(recordingHook)
+		if _, err := srv.Client(twist.WithHook(h), twist.WithRetryPolicy(nil)).Workspaces(ctx); err == nil {
+			t.Fatal("response with bad Content-Type was accepted")
+		}
+		if len(h.responses) != 1 {
+			t.Fatalf("got %d response events, want 1", len(h.responses))
+		}
+		// status is fine, but the attempt still failed
+		if e := h.responses[0]; e.StatusCode != 200 || e.Err == nil || !strings.Contains(e.Err.Error(), "Content-Type") {
+			t.Fatalf("unexpected response event: %+v", e)
+		}
+	})
+}
+
+func TestNewSlogHook(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/workspaces/get", Times: 1, Status: 503})
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := srv.Client(twist.WithHook(twist.NewSlogHook(l))).Workspaces(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for dec := json.NewDecoder(&buf); dec.More(); {
+		var rec struct{ Level, Msg string }
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec.Level+" "+rec.Msg)
+	}
+	want := []string{
+		"DEBUG twist request",
+		"WARN twist response",
+		"WARN twist retry",
+		"DEBUG twist request",
+		"DEBUG twist response",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got log records:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,235 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// InboxQuery holds parameters for Client.Inbox and Client.InboxPaginator.
+type InboxQuery struct {
+	WorkspaceID  uint64    // required
+	Since, Until time.Time // only return threads updated within this window, if set
+	Archived     bool      // return archived threads instead of active ones
+	UnreadOnly   bool      // only return threads with unread activity
+}
+
+// Inbox returns the first page of threads in user's inbox, most recently
+// updated first. Use InboxPaginator to get all of them.
+func (c *Client) Inbox(ctx context.Context, q InboxQuery) ([]Thread, error) {
+	return c.InboxPaginator(q).Page(ctx)
+}
+
+// InboxPaginator returns InboxPaginator that fetches all threads of user's
+// inbox, most recently updated first.
+//
+// Inbox is paginated by thread update time, so paginator works on a
+// best-effort basis: it may miss threads updated between per-page API calls.
+// Threads sharing the same update time on page boundaries are returned once,
+// but if more than a page of threads share the same update time, paginator
+// cannot get past them and Page fails.
+func (c *Client) InboxPaginator(q InboxQuery) *InboxPaginator {
+	var ts uint64
+	if t := q.Until.Unix(); !q.Until.IsZero() && t > 0 {
+		ts = uint64(t)
+	}
+	return &InboxPaginator{c: c, query: q, olderThanTs: ts}
+}
+
+// InboxPaginator fetches threads of user's inbox.
+//
+// Typical usage:
+//
+//	p := client.InboxPaginator(twist.InboxQuery{WorkspaceID: 1234})
+//	for p.Next() {
+//		threads, err := p.Page(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		doSomethingWithThreads(threads)
+//	}
+type InboxPaginator struct {
+	c           *Client
+	query       InboxQuery
+	olderThanTs uint64
+	seen        []uint64 // ids of threads already returned with olderThanTs update time
+	done        bool
+}
+
+// Next reports whether there's another page to load. It only returns false
+// once all threads are fetched with the Page method.
+func (ip *InboxPaginator) Next() bool { return !ip.done }
+
+// Page returns next portion of inbox threads. With UnreadOnly query, pages
+// may be shorter than others, or even empty, before the last one.
+func (ip *InboxPaginator) Page(ctx context.Context) ([]Thread, error) {
+	if ip.done {
+		return nil, errors.New("all pages already read")
+	}
+	threads, err := ip.c.getInboxPage(ctx, ip.query, ip.olderThanTs)
+	if err != nil {
+		return nil, err
+	}
+	full := len(threads) == maxThreadsPerPage
+	// API uses closed interval, so threads on the previous page boundary
+	// are returned again
+	threads = slices.DeleteFunc(threads, func(t Thread) bool {
+		return t.TsUpdated == ip.olderThanTs && slices.Contains(ip.seen, t.Id)
+	})
+	if full && len(threads) == 0 {
+		return nil, fmt.Errorf("more than %d inbox threads share update time %d, cannot paginate past them",
+			maxThreadsPerPage, ip.olderThanTs)
+	}
+	ip.done = !full
+	var minTs uint64
+	for _, t := range threads {
+		if t.TsUpdated != 0 && (minTs == 0 || t.TsUpdated < minTs) {
+			minTs = t.TsUpdated
+		}
+	}
+	if minTs == 0 {
+		ip.done = true // no progress is possible
+	} else {
+		if minTs != ip.olderThanTs {
+			ip.olderThanTs, ip.seen = minTs, ip.seen[:0]
+		}
+		for _, t := range threads {
+			if t.TsUpdated == minTs {
+				ip.seen = append(ip.seen, t.Id)
+			}
+		}
+	}
+	if ip.query.UnreadOnly {
+		unread := threads[:0]
+		for _, t := range threads {
+			if t.Unread {
+				unread = append(unread, t)
+			}
+		}
+		threads = unread
+	}
+	return threads, nil
+}
+
+// All returns an iterator over the remaining inbox threads, fetching pages
+// as needed. Breaking out of the loop stops fetching. If fetching a page
+// fails, iterator yields the error and stops.
+func (ip *InboxPaginator) All(ctx context.Context) iter.Seq2[Thread, error] {
+	return paginate(ctx, ip)
+}
+
+func (c *Client) getInboxPage(ctx context.Context, q InboxQuery, olderThanTs uint64) ([]Thread, error) {
+	if q.WorkspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(q.WorkspaceID, 10))
+	vals.Add("limit", strconv.Itoa(maxThreadsPerPage))
+	if olderThanTs != 0 {
+		vals.Add("older_than_ts", strconv.FormatUint(olderThanTs, 10))
+	}
+	if t := q.Since.Unix(); !q.Since.IsZero() && t > 0 {
+		vals.Add("newer_than_ts", strconv.FormatInt(t, 10))
+	}
+	if q.Archived {
+		vals.Add("archived", "true")
+	}
+	var out []Thread
+	if err := c.get(ctx, "v3/inbox/get", vals, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnreadCount returns the number of threads with unread activity in user's
+// inbox.
+func (c *Client) UnreadCount(ctx context.Context, workspaceID uint64) (int, error) {
+	if workspaceID == 0 {
+		return 0, errors.New("invalid workspace id")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(workspaceID, 10))
+	var out int
+	if err := c.get(ctx, "v3/inbox/get_count", vals, &out); err != nil {
+		return 0, err
+	}
+	return out, nil
+}
+
+// ArchiveInbox archives a thread in user's inbox, hiding it from Inbox
+// results until it gets new activity or is unarchived with UnarchiveInbox.
+// It is the same as ArchiveThread.
+func (c *Client) ArchiveInbox(ctx context.Context, threadID uint64) error {
+	return c.ArchiveThread(ctx, threadID)
+}
+
+// UnarchiveInbox moves a previously archived thread back to user's inbox. It
+// is the same as UnarchiveThread.
+func (c *Client) UnarchiveInbox(ctx context.Context, threadID uint64) error {
+	return c.UnarchiveThread(ctx, threadID)
+}
+
+// ArchiveAllInbox archives all threads in user's inbox that were last
+// updated before a given time, or all of them if olderThan is zero. Twist has
+// no matching bulk unarchive call: use UnarchiveInbox for individual threads.
+func (c *Client) ArchiveAllInbox(ctx context.Context, workspaceID uint64, olderThan time.Time) error {
+	if workspaceID == 0 {
+		return errors.New("invalid workspace id")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(workspaceID, 10))
+	if t := olderThan.Unix(); !olderThan.IsZero() && t > 0 {
+		vals.Add("older_than_ts", strconv.FormatInt(t, 10))
+	}
+	return c.post(ctx, "v3/inbox/archive_all", vals, nil)
+}
+
+// MarkThreadRead marks thread as read up to a comment with a given
+// OrderIndex. Use Thread.LastObjIndex to mark the whole thread as read.
+func (c *Client) MarkThreadRead(ctx context.Context, threadID uint64, objIndex int) error {
+	return c.markThread(ctx, "v3/threads/mark_read", threadID, objIndex)
+}
+
+// MarkThreadUnread marks thread as unread starting from a comment with a
+// given OrderIndex.
+func (c *Client) MarkThreadUnread(ctx context.Context, threadID uint64, objIndex int) error {
+	return c.markThread(ctx, "v3/threads/mark_unread", threadID, objIndex)
+}
+
+func (c *Client) markThread(ctx context.Context, endpoint string, threadID uint64, objIndex int) error {
+	if threadID == 0 {
+		return errors.New("invalid thread id")
+	}
+	if objIndex < 0 {
+		return errors.New("negative obj_index")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(threadID, 10))
+	vals.Add("obj_index", strconv.Itoa(objIndex))
+	return c.post(ctx, endpoint, vals, nil)
+}
+
+// MuteThread mutes notifications about a thread for a given duration,
+// rounded up to a whole minute.
+func (c *Client) MuteThread(ctx context.Context, threadID uint64, d time.Duration) error {
+	if threadID == 0 {
+		return errors.New("invalid thread id")
+	}
+	if d <= 0 {
+		return errors.New("mute duration must be positive")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(threadID, 10))
+	vals.Add("minutes", strconv.FormatInt(int64((d+time.Minute-1)/time.Minute), 10))
+	return c.post(ctx, "v3/threads/mute", vals, nil)
+}
+
+// UnmuteThread restores notifications about a previously muted thread.
+func (c *Client) UnmuteThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/threads/unmute", "thread", threadID)
+}
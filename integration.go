@@ -0,0 +1,91 @@
+package twist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Integration posts content to Twist using an incoming webhook integration
+// install URL, which doesn't require an OAuth token. Create it with
+// NewIntegration.
+//
+// See https://developer.twist.com/v3/#incoming-webhooks for details.
+type Integration struct {
+	installURL string
+	c          *Client
+}
+
+// NewIntegration returns Integration that posts to a given install URL,
+// which Twist shows once integration is installed to a channel or a thread.
+//
+// Options configure HTTP client, user agent, retry policy, rate limiting,
+// and hooks the same way as for New; WithBaseURL has no effect.
+func NewIntegration(installURL string, opts ...Option) (*Integration, error) {
+	// errors must not include installURL, since it carries install token
+	u, err := url.Parse(installURL)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("invalid install url: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return nil, errors.New("invalid install url: want absolute http or https url")
+	}
+	return &Integration{installURL: installURL, c: New("", opts...)}, nil
+}
+
+// IntegrationPost holds content to post with Integration.Post.
+type IntegrationPost struct {
+	Content string // required
+
+	// Title, if set, makes integration start a new thread with this title,
+	// otherwise content is posted as a comment to the thread integration
+	// was installed to, or to ThreadID.
+	Title    string
+	ThreadID uint64
+
+	// SendAsUser makes post appear as written by the user who installed
+	// integration, rather than by integration itself.
+	SendAsUser bool
+}
+
+// Post posts content to Twist. Failed requests are retried as Integration's
+// RetryPolicy decides, but only if Twist reports they were not processed, see
+// RetryPolicy. Unexpected responses are reported as *APIError.
+func (in *Integration) Post(ctx context.Context, p IntegrationPost) error {
+	if p.Content == "" {
+		return errors.New("empty content")
+	}
+	body, err := json.Marshal(struct {
+		Content    string `json:"content"`
+		Title      string `json:"title,omitempty"`
+		ThreadID   uint64 `json:"thread_id,omitempty"`
+		SendAsUser bool   `json:"send_as_user,omitempty"`
+	}{p.Content, p.Title, p.ThreadID, p.SendAsUser})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.installURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerContentType, jsonContentType)
+	if in.c.userAgent != "" {
+		req.Header.Set("User-Agent", in.c.userAgent)
+	}
+	rc, err := in.c.doRequestWithRetries("integration", req, false, false)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
@@ -0,0 +1,83 @@
+package twist
+
+import (
+	"context"
+	"iter"
+)
+
+// AllThreads returns an iterator over all threads of a channel. It is a
+// shortcut for ThreadsPaginator(channelID).All(ctx).
+//
+// Typical usage:
+//
+//	for thread, err := range client.AllThreads(ctx, 1234) {
+//		if err != nil {
+//			return err
+//		}
+//		doSomethingWithThread(thread)
+//	}
+func (c *Client) AllThreads(ctx context.Context, channelID uint64) iter.Seq2[Thread, error] {
+	return c.ThreadsPaginator(channelID).All(ctx)
+}
+
+// AllComments returns an iterator over all comments of a thread. It is a
+// shortcut for CommentsPaginator(threadID).All(ctx).
+func (c *Client) AllComments(ctx context.Context, threadID uint64) iter.Seq2[Comment, error] {
+	return c.CommentsPaginator(threadID).All(ctx)
+}
+
+// AllConversationMessages returns an iterator over all messages of a
+// conversation. It is a shortcut for
+// ConversationMessagesPaginator(conversationID).All(ctx).
+func (c *Client) AllConversationMessages(ctx context.Context, conversationID uint64) iter.Seq2[ConversationMessage, error] {
+	return c.ConversationMessagesPaginator(conversationID).All(ctx)
+}
+
+// All returns an iterator over the remaining threads, fetching pages as
+// needed. Breaking out of the loop stops fetching. If fetching a page fails,
+// iterator yields the error and stops. Paginator is advanced past a page as
+// soon as it's fetched, see ThreadsPaginator.MarshalText.
+func (cp *ThreadsPaginator) All(ctx context.Context) iter.Seq2[Thread, error] {
+	return paginate(ctx, cp)
+}
+
+// All returns an iterator over the remaining comments, fetching pages as
+// needed. Breaking out of the loop stops fetching. If fetching a page fails,
+// iterator yields the error and stops. Paginator is advanced past a page as
+// soon as it's fetched, see ThreadsPaginator.MarshalText.
+func (tp *CommentsPaginator) All(ctx context.Context) iter.Seq2[Comment, error] {
+	return paginate(ctx, tp)
+}
+
+// All returns an iterator over the remaining messages, fetching pages as
+// needed. Breaking out of the loop stops fetching. If fetching a page fails,
+// iterator yields the error and stops. Paginator is advanced past a page as
+// soon as it's fetched, see ThreadsPaginator.MarshalText.
+func (mp *ConversationMessagesPaginator) All(ctx context.Context) iter.Seq2[ConversationMessage, error] {
+	return paginate(ctx, mp)
+}
+
+type paginator[T any] interface {
+	Next() bool
+	Page(context.Context) ([]T, error)
+}
+
+// paginate returns an iterator that yields items of p pages one by one. If
+// fetching a page fails, iterator yields the error along with a zero item.
+func paginate[T any](ctx context.Context, p paginator[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for p.Next() {
+			items, err := p.Page(ctx)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
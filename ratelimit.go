@@ -0,0 +1,74 @@
+package twist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRateLimit makes Client limit the rate of API requests it sends to rps
+// requests per second on average, allowing bursts of up to burst requests.
+// The limit is shared by all methods and paginators of the Client, so it
+// applies to Client used from multiple goroutines concurrently. Each retry
+// attempt counts as a separate request.
+//
+// If rps is not positive, requests are not limited.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newLimiter(rps, burst)
+	}
+}
+
+// limiter is a token bucket rate limiter.
+type limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rps float64, burst int) *limiter {
+	b := float64(max(burst, 1))
+	return &limiter{rate: rps, burst: b, tokens: b}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (l *limiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	// take a token in advance, even if it's not there yet: negative balance
+	// makes concurrent callers queue up behind this one
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens = min(l.burst, l.tokens+1) // return unused token
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,27 @@
+package twist
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_limiter(t *testing.T) {
+	l := newLimiter(20, 2)
+	ctx := context.Background()
+	begin := time.Now()
+	for range 4 {
+		if err := l.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// first 2 requests fit into the burst, 2 more take 50ms each
+	if d := time.Since(begin); d < 90*time.Millisecond {
+		t.Fatalf("4 requests took %v, want at least 100ms", d)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
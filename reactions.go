@@ -0,0 +1,70 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// ReactionTarget identifies an object to react to. Create it with
+// ThreadTarget, CommentTarget, or MessageTarget.
+type ReactionTarget struct {
+	param string // API argument name
+	id    uint64
+}
+
+// ThreadTarget returns ReactionTarget for a thread.
+func ThreadTarget(threadID uint64) ReactionTarget { return ReactionTarget{"thread_id", threadID} }
+
+// CommentTarget returns ReactionTarget for a thread comment.
+func CommentTarget(commentID uint64) ReactionTarget { return ReactionTarget{"comment_id", commentID} }
+
+// MessageTarget returns ReactionTarget for a conversation message.
+func MessageTarget(messageID uint64) ReactionTarget { return ReactionTarget{"message_id", messageID} }
+
+func (t ReactionTarget) values() (url.Values, error) {
+	if t.param == "" || t.id == 0 {
+		return nil, errors.New("invalid reaction target")
+	}
+	vals := make(url.Values)
+	vals.Add(t.param, strconv.FormatUint(t.id, 10))
+	return vals, nil
+}
+
+// Reactions returns reactions to a given object.
+func (c *Client) Reactions(ctx context.Context, target ReactionTarget) (Reactions, error) {
+	vals, err := target.values()
+	if err != nil {
+		return nil, err
+	}
+	var out Reactions
+	if err := c.get(ctx, "v3/reactions/get", vals, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddReaction adds a reaction with a given emoji, i.e. "👍", on behalf of the
+// user that owns Client's token.
+func (c *Client) AddReaction(ctx context.Context, target ReactionTarget, emoji string) error {
+	return c.reactionAction(ctx, "v3/reactions/add", target, emoji)
+}
+
+// RemoveReaction removes a reaction with a given emoji previously added by
+// the user that owns Client's token.
+func (c *Client) RemoveReaction(ctx context.Context, target ReactionTarget, emoji string) error {
+	return c.reactionAction(ctx, "v3/reactions/remove", target, emoji)
+}
+
+func (c *Client) reactionAction(ctx context.Context, endpoint string, target ReactionTarget, emoji string) error {
+	if emoji == "" {
+		return errors.New("empty reaction")
+	}
+	vals, err := target.values()
+	if err != nil {
+		return err
+	}
+	vals.Add("reaction", emoji)
+	return c.post(ctx, endpoint, vals, nil)
+}
@@ -0,0 +1,180 @@
+package twist
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides whether a failed API call should be retried.
+//
+// Calls that create, update, or remove data are never retried after network
+// errors and 5xx responses, since they may fail after server has already
+// applied them: Client only consults RetryPolicy for such calls if they got
+// 429 Too Many Requests, or 503 Service Unavailable with Retry-After header.
+type RetryPolicy interface {
+	// Retry is called after each failed attempt to send request, attempt
+	// is 1 for the first one. If the attempt got a response, resp is non-nil
+	// and has its body already closed; err is a reason of failure, which is
+	// an *APIError for unexpected response statuses. Retry returns a delay
+	// to wait before the next attempt, and whether to make one.
+	Retry(attempt int, req *http.Request, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// WithRetryPolicy makes Client use provided RetryPolicy instead of the one
+// returned by DefaultRetryPolicy. If p is nil, Client makes no retries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retryPolicy = p }
+}
+
+// WithRetryHook makes Client call fn before each retry of an API call. It is
+// a shortcut for WithHook with a Hook only implementing OnRetry.
+func WithRetryHook(fn func(context.Context, RetryEvent)) Option {
+	return func(c *Client) {
+		if fn != nil {
+			c.hooks = append(c.hooks, retryHookFunc(fn))
+		}
+	}
+}
+
+// RetryEvent describes a failed API call attempt that is about to be
+// retried.
+type RetryEvent struct {
+	Endpoint string        // API endpoint called, i.e. "v3/threads/getone"
+	Attempt  int           // number of failed attempt, starting from 1
+	Delay    time.Duration // delay before the next attempt
+	Err      error         // reason of failure
+}
+
+// DefaultRetryPolicy returns RetryPolicy that Client uses unless configured
+// otherwise with WithRetryPolicy. It makes up to 10 attempts with
+// exponentially growing delays starting from 500ms, retries network errors
+// for idempotent requests, and honors Retry-After header.
+func DefaultRetryPolicy() *BackoffPolicy {
+	return &BackoffPolicy{
+		MaxAttempts:        10,
+		BaseDelay:          500 * time.Millisecond,
+		MaxDelay:           30 * time.Second,
+		Jitter:             0.2,
+		RetryNetworkErrors: true,
+	}
+}
+
+// BackoffPolicy is a RetryPolicy with exponential backoff and jitter.
+//
+// Delay before the next attempt doubles after each attempt, starting from
+// BaseDelay and limited by MaxDelay. If response has a Retry-After header,
+// its value is used instead, also limited by MaxDelay.
+type BackoffPolicy struct {
+	MaxAttempts int           // total number of attempts, including the first one
+	BaseDelay   time.Duration // delay after the first failed attempt
+	MaxDelay    time.Duration // upper limit of delay, if positive
+	Jitter      float64       // fraction of delay to randomly shave off, in [0, 1] range
+
+	// RetryStatus reports whether response with a given status code should be
+	// retried. If nil, 429 Too Many Requests and 5xx statuses are retried.
+	RetryStatus func(code int) bool
+
+	// RetryNetworkErrors enables retries of requests that failed without
+	// getting a response. Only idempotent (GET and HEAD) requests are retried.
+	RetryNetworkErrors bool
+}
+
+// Retry implements RetryPolicy.
+func (p *BackoffPolicy) Retry(attempt int, req *http.Request, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	switch {
+	case resp == nil:
+		if !p.RetryNetworkErrors || !isIdempotent(req.Method) {
+			return 0, false
+		}
+	case p.RetryStatus != nil:
+		if !p.RetryStatus(resp.StatusCode) {
+			return 0, false
+		}
+	default:
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError {
+			return 0, false
+		}
+	}
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxDelay > 0 {
+				d = min(d, p.MaxDelay)
+			}
+			return d, true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns delay after a given failed attempt. Without MaxDelay, delay
+// saturates at the maximum time.Duration instead of overflowing.
+func (p *BackoffPolicy) backoff(attempt int) time.Duration {
+	limit := time.Duration(math.MaxInt64)
+	if p.MaxDelay > 0 {
+		limit = p.MaxDelay
+	}
+	d := min(p.BaseDelay, limit)
+	for i := 1; i < attempt && d > 0 && d < limit; i++ {
+		if d > limit/2 {
+			d = limit
+		} else {
+			d *= 2
+		}
+	}
+	if j := min(max(p.Jitter, 0), 1); j > 0 {
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+	return d
+}
+
+// parseRetryAfter parses Retry-After header value, which is either a number
+// of seconds, or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if n, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(n) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// attemptsExhausted reports whether p refuses to retry after a given attempt
+// because it ran out of attempts, rather than because of the failure kind.
+func attemptsExhausted(p RetryPolicy, attempt int) bool {
+	bp, ok := p.(*BackoffPolicy)
+	return ok && attempt >= bp.MaxAttempts
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// retryableWrite reports whether a failed request that is not safe to repeat
+// can still be retried, because server reported it was not processed:
+// either rate-limited it, or asked to retry it later.
+func retryableWrite(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
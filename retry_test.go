@@ -0,0 +1,142 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "0", want: 0, ok: true},
+		{value: " 5 ", want: 5 * time.Second, ok: true},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second, ok: true},
+		{value: now.Add(-time.Hour).Format(http.TimeFormat), want: 0, ok: true},
+		{value: "-1", ok: false},
+		{value: "soon", ok: false},
+	} {
+		got, ok := parseRetryAfter(tc.value, now)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestBackoffPolicy_backoff(t *testing.T) {
+	p := &BackoffPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range map[int]time.Duration{
+		1:   100 * time.Millisecond,
+		2:   200 * time.Millisecond,
+		3:   400 * time.Millisecond,
+		4:   800 * time.Millisecond,
+		5:   time.Second,
+		100: time.Second,
+	} {
+		if got := p.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	// without MaxDelay, delay saturates instead of overflowing
+	unbounded := &BackoffPolicy{BaseDelay: time.Second}
+	prev := unbounded.backoff(1)
+	for attempt := 2; attempt <= 100; attempt++ {
+		d := unbounded.backoff(attempt)
+		if d < prev {
+			t.Fatalf("backoff(%d) = %v is less than backoff(%d) = %v", attempt, d, attempt-1, prev)
+		}
+		prev = d
+	}
+	if want := time.Duration(math.MaxInt64); prev != want {
+		t.Fatalf("backoff(100) without MaxDelay = %v, want %v", prev, want)
+	}
+	if d := unbounded.backoff(31); d != time.Second<<30 {
+		t.Fatalf("backoff(31) without MaxDelay = %v, want %v", d, time.Second<<30)
+	}
+
+	p.Jitter = 0.25
+	for range 1000 {
+		if d := p.backoff(2); d < 150*time.Millisecond || d > 200*time.Millisecond {
+			t.Fatalf("backoff with jitter is %v, want within [150ms, 200ms]", d)
+		}
+	}
+}
+
+func TestBackoffPolicy_Retry(t *testing.T) {
+	p := &BackoffPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, RetryNetworkErrors: true}
+	response := func(code int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: code, Header: make(http.Header)}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+	netErr := errors.New("connection reset")
+	for _, tc := range []struct {
+		name    string
+		method  string
+		attempt int
+		resp    *http.Response
+		want    time.Duration
+		ok      bool
+	}{
+		{name: "GET network error", method: http.MethodGet, attempt: 1, want: time.Second, ok: true},
+		{name: "POST network error", method: http.MethodPost, attempt: 1},
+		{name: "429", method: http.MethodPost, attempt: 2, resp: response(429, ""), want: 2 * time.Second, ok: true},
+		{name: "503", method: http.MethodGet, attempt: 1, resp: response(503, ""), want: time.Second, ok: true},
+		{name: "404", method: http.MethodGet, attempt: 1, resp: response(404, "")},
+		{name: "Retry-After", method: http.MethodGet, attempt: 1, resp: response(503, "7"), want: 7 * time.Second, ok: true},
+		{name: "Retry-After above MaxDelay", method: http.MethodGet, attempt: 1, resp: response(429, "36000"), want: time.Minute, ok: true},
+		{name: "attempts exhausted", method: http.MethodGet, attempt: 3, resp: response(503, "")},
+	} {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		var err error = netErr
+		if tc.resp != nil {
+			err = &APIError{StatusCode: tc.resp.StatusCode}
+		}
+		got, ok := p.Retry(tc.attempt, req, tc.resp, err)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%s: got %v, %v, want %v, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestClient_retryErrorText(t *testing.T) {
+	var calls int
+	var statuses []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[min(calls, len(statuses)-1)])
+		calls++
+	}))
+	defer srv.Close()
+	c := New("token", WithBaseURL(srv.URL),
+		WithRetryPolicy(&BackoffPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	ctx := context.Background()
+
+	calls, statuses = 0, []int{503, 404}
+	err := c.get(ctx, "v3/threads/getone", nil, nil)
+	if !IsNotFound(err) || strings.Contains(err.Error(), "giving up") {
+		t.Fatalf("got error %q, want not found error without giving up wording", err)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr); apiErr.Retries != 1 {
+		t.Fatalf("error reports %d retries, want 1", apiErr.Retries)
+	}
+
+	calls, statuses = 0, []int{503}
+	err = c.get(ctx, "v3/threads/getone", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "giving up after 2 retries") {
+		t.Fatalf("got error %q, want one about giving up after 2 retries", err)
+	}
+}
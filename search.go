@@ -0,0 +1,163 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SearchQuery holds search parameters for Client.Search and
+// Client.SearchPaginator. Fields with zero values don't restrict results.
+type SearchQuery struct {
+	WorkspaceID uint64 // workspace to search in, required
+	Query       string // text to search for, required
+
+	ChannelIDs      []uint64 // only search in these channels
+	ConversationIDs []uint64 // only search in these conversations
+	AuthorIDs       []uint64 // only search for content posted by these users
+	Since, Until    time.Time
+	Type            SearchResultType // only search for results of this type
+	TitleOnly       bool             // only search in thread titles
+}
+
+// SearchResultType tells what kind of object search result points to.
+type SearchResultType string
+
+const (
+	SearchThread  SearchResultType = "thread"
+	SearchComment SearchResultType = "comment"
+	SearchMessage SearchResultType = "message"
+)
+
+// SearchResult is a single search match. Depending on Type, either ThreadID
+// and optionally CommentID, or ConversationID and MessageID are set.
+type SearchResult struct {
+	Type           SearchResultType `json:"type"`
+	ChannelID      uint64           `json:"channel_id"`
+	ThreadID       uint64           `json:"thread_id"`
+	CommentID      uint64           `json:"comment_id"`
+	ConversationID uint64           `json:"conversation_id"`
+	MessageID      uint64           `json:"message_id"`
+	Title          string           `json:"title"`
+	Snippet        string           `json:"snippet"`
+	SnippetCreator uint64           `json:"snippet_creator"`
+	TsSnippet      uint64           `json:"snippet_last_updated"`
+}
+
+// SnippetAt is a convenience method to convert TsSnippet field to time.
+func (r *SearchResult) SnippetAt() time.Time { return time.Unix(int64(r.TsSnippet), 0) }
+
+// Search returns the first page of search results. Use SearchPaginator to
+// get all of them.
+func (c *Client) Search(ctx context.Context, q SearchQuery) ([]SearchResult, error) {
+	page, err := c.getSearchPage(ctx, q, "")
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// SearchPaginator returns SearchPaginator that fetches all results of a
+// search query.
+func (c *Client) SearchPaginator(q SearchQuery) *SearchPaginator {
+	return &SearchPaginator{c: c, query: q}
+}
+
+// SearchPaginator fetches search results.
+//
+// Typical usage:
+//
+//	p := client.SearchPaginator(twist.SearchQuery{WorkspaceID: 1234, Query: "INC-42"})
+//	for p.Next() {
+//		results, err := p.Page(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		doSomethingWithResults(results)
+//	}
+type SearchPaginator struct {
+	c      *Client
+	query  SearchQuery
+	cursor string
+	done   bool
+}
+
+// Next reports whether there's another page to load. It only returns false
+// once all results are fetched with the Page method.
+func (sp *SearchPaginator) Next() bool { return !sp.done }
+
+// Page returns next portion of search results.
+func (sp *SearchPaginator) Page(ctx context.Context) ([]SearchResult, error) {
+	if sp.done {
+		return nil, errors.New("all pages already read")
+	}
+	page, err := sp.c.getSearchPage(ctx, sp.query, sp.cursor)
+	if err != nil {
+		return nil, err
+	}
+	sp.cursor = page.NextCursor
+	sp.done = !page.HasMore || page.NextCursor == ""
+	return page.Items, nil
+}
+
+// All returns an iterator over the remaining search results, fetching pages
+// as needed. Breaking out of the loop stops fetching. If fetching a page
+// fails, iterator yields the error and stops.
+func (sp *SearchPaginator) All(ctx context.Context) iter.Seq2[SearchResult, error] {
+	return paginate(ctx, sp)
+}
+
+type searchPage struct {
+	Items      []SearchResult `json:"items"`
+	HasMore    bool           `json:"has_more"`
+	NextCursor string         `json:"next_cursor_mark"`
+}
+
+// getSearchPage returns a page of search results starting from a given
+// cursor, which is empty for the first page.
+func (c *Client) getSearchPage(ctx context.Context, q SearchQuery, cursor string) (*searchPage, error) {
+	if q.WorkspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	if q.Query == "" {
+		return nil, errors.New("empty search query")
+	}
+	vals := make(url.Values)
+	vals.Add("workspace_id", strconv.FormatUint(q.WorkspaceID, 10))
+	vals.Add("query", q.Query)
+	vals.Add("limit", strconv.Itoa(maxSearchResultsPerPage))
+	for key, ids := range map[string][]uint64{
+		"channel_ids":      q.ChannelIDs,
+		"conversation_ids": q.ConversationIDs,
+		"author_ids":       q.AuthorIDs,
+	} {
+		if err := addJSON(vals, key, ids); err != nil {
+			return nil, err
+		}
+	}
+	if !q.Since.IsZero() {
+		vals.Add("date_from", q.Since.UTC().Format(time.DateOnly))
+	}
+	if !q.Until.IsZero() {
+		vals.Add("date_to", q.Until.UTC().Format(time.DateOnly))
+	}
+	if q.Type != "" {
+		vals.Add("type", string(q.Type))
+	}
+	if q.TitleOnly {
+		vals.Add("title_only", "true")
+	}
+	if cursor != "" {
+		vals.Add("cursor_mark", cursor)
+	}
+	var out searchPage
+	if err := c.get(ctx, "v3/search/query", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+const maxSearchResultsPerPage = 100
@@ -0,0 +1,171 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// NewThread holds parameters of a thread to create with Client.AddThread.
+type NewThread struct {
+	ChannelID   uint64 // channel to post thread to, required
+	Title       string // required
+	Content     string
+	Recipients  []uint64 // ids of users to notify
+	Groups      []uint64 // ids of groups to notify
+	Attachments []Attachment
+
+	// SendAsIntegration makes thread appear as posted by the integration
+	// that owns the token, instead of the user.
+	SendAsIntegration bool
+}
+
+// AddThread creates a new thread and returns it.
+func (c *Client) AddThread(ctx context.Context, t NewThread) (*Thread, error) {
+	if t.ChannelID == 0 {
+		return nil, errors.New("invalid channel id")
+	}
+	if t.Title == "" {
+		return nil, errors.New("empty thread title")
+	}
+	vals := make(url.Values)
+	vals.Add("channel_id", strconv.FormatUint(t.ChannelID, 10))
+	vals.Add("title", t.Title)
+	vals.Add("content", t.Content)
+	if err := addJSON(vals, "recipients", t.Recipients); err != nil {
+		return nil, err
+	}
+	if err := addJSON(vals, "groups", t.Groups); err != nil {
+		return nil, err
+	}
+	if err := addJSON(vals, "attachments", t.Attachments); err != nil {
+		return nil, err
+	}
+	if t.SendAsIntegration {
+		vals.Add("send_as_integration", "true")
+	}
+	var out Thread
+	if err := c.post(ctx, "v3/threads/add", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ThreadUpdate holds changes to apply to an existing thread with
+// Client.UpdateThread. Fields with zero values are left unchanged.
+type ThreadUpdate struct {
+	Id          uint64 // thread id, required
+	Title       string
+	Content     string
+	Attachments []Attachment
+}
+
+// UpdateThread updates an existing thread and returns its new version.
+func (c *Client) UpdateThread(ctx context.Context, u ThreadUpdate) (*Thread, error) {
+	if u.Id == 0 {
+		return nil, errors.New("invalid thread id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(u.Id, 10))
+	if u.Title != "" {
+		vals.Add("title", u.Title)
+	}
+	if u.Content != "" {
+		vals.Add("content", u.Content)
+	}
+	if err := addJSON(vals, "attachments", u.Attachments); err != nil {
+		return nil, err
+	}
+	var out Thread
+	if err := c.post(ctx, "v3/threads/update", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveThread permanently removes a thread with all its comments.
+func (c *Client) RemoveThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/threads/remove", "thread", threadID)
+}
+
+// ArchiveThread archives a thread in user's inbox.
+func (c *Client) ArchiveThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/inbox/archive", "thread", threadID)
+}
+
+// UnarchiveThread moves a previously archived thread back to user's inbox.
+func (c *Client) UnarchiveThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/inbox/unarchive", "thread", threadID)
+}
+
+// PinThread pins a thread to the top of its channel.
+func (c *Client) PinThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/threads/pin", "thread", threadID)
+}
+
+// UnpinThread unpins a previously pinned thread.
+func (c *Client) UnpinThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/threads/unpin", "thread", threadID)
+}
+
+// StarThread stars a thread for the user that owns Client's token.
+func (c *Client) StarThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/threads/star", "thread", threadID)
+}
+
+// UnstarThread unstars a previously starred thread.
+func (c *Client) UnstarThread(ctx context.Context, threadID uint64) error {
+	return c.postID(ctx, "v3/threads/unstar", "thread", threadID)
+}
+
+// PinnedThreads returns all pinned threads of a channel. Pins belong to
+// a channel and are shared by all its members, so they are listed per
+// channel.
+func (c *Client) PinnedThreads(ctx context.Context, channelID uint64) ([]Thread, error) {
+	return c.filteredThreads(ctx, channelID, "is_pinned", func(t *Thread) bool { return t.Pinned })
+}
+
+// StarredThreads returns threads starred by the user that owns Client's
+// token in all channels of a workspace that the user can see. Twist API only
+// lists threads per channel, so this makes at least one call per channel; use
+// ChannelStarredThreads if you only need one channel.
+func (c *Client) StarredThreads(ctx context.Context, workspaceID uint64) ([]Thread, error) {
+	channels, err := c.Channels(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	var out []Thread
+	for _, ch := range channels {
+		threads, err := c.ChannelStarredThreads(ctx, ch.Id)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", ch.Id, err)
+		}
+		out = append(out, threads...)
+	}
+	return out, nil
+}
+
+// ChannelStarredThreads returns all threads of a channel starred by the user
+// that owns Client's token.
+func (c *Client) ChannelStarredThreads(ctx context.Context, channelID uint64) ([]Thread, error) {
+	return c.filteredThreads(ctx, channelID, "is_starred", func(t *Thread) bool { return t.Starred })
+}
+
+// filteredThreads returns all channel threads selected by API filter_by
+// argument. Results are additionally checked with keep function, so that
+// only matching threads are returned even if API ignores the filter.
+func (c *Client) filteredThreads(ctx context.Context, channelID uint64, filter string, keep func(*Thread) bool) ([]Thread, error) {
+	p := &ThreadsPaginator{c: c, channelID: channelID, filter: filter}
+	var out []Thread
+	for t, err := range p.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		if keep(&t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
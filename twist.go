@@ -19,39 +19,119 @@ import (
 
 // Client is a Twist API client.
 type Client struct {
-	token string
+	token      string
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+
+	retryPolicy RetryPolicy
+	hooks       []Hook
+
+	limiter *limiter // nil if requests are not rate limited
 }
 
 // New returns Client that calls Twist API using provided token for
 // authentication.
 //
 // See https://developer.twist.com/v3/#authentication for details.
-func New(token string) *Client { return &Client{token: token} }
+func New(token string, opts ...Option) *Client {
+	c := &Client{
+		token:      token,
+		baseURL:    defaultBaseURL,
+		userAgent:  defaultUserAgent,
+		httpClient: http.DefaultClient,
+
+		retryPolicy: DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Option configures Client created with New.
+type Option func(*Client)
+
+// WithBaseURL makes Client send API requests to a given base URL instead of
+// https://api.twist.com/api. Base URL must not include API version suffix,
+// as Client calls endpoints of different API versions.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimSuffix(baseURL, "/") }
+}
+
+// WithHTTPClient makes Client use provided http.Client instead of
+// http.DefaultClient to send requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.httpClient = hc
+		}
+	}
+}
+
+// WithUserAgent makes Client use provided User-Agent header value in API
+// requests.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
 
+// User is a Twist user as seen in a given workspace.
+//
+// See https://developer.twist.com/v3/#users for details.
 type User struct {
-	Id        uint64 `json:"id"`
-	Name      string `json:"name"`
-	ShortName string `json:"short_name"`
+	Id          uint64      `json:"id"`
+	Name        string      `json:"name"`
+	ShortName   string      `json:"short_name"`
+	FirstName   string      `json:"first_name"`
+	Email       string      `json:"email"`
+	Timezone    string      `json:"timezone"` // IANA time zone name, i.e. "Europe/London"
+	Lang        string      `json:"lang"`
+	Profession  string      `json:"profession"`
+	ContactInfo string      `json:"contact_info"`
+	UserType    string      `json:"user_type"` // one of "USER", "ADMIN", "GUEST"
+	Bot         bool        `json:"bot"`
+	Removed     bool        `json:"removed"` // user was removed from the workspace
+	AvatarURLs  *AvatarURLs `json:"avatar_urls"`
+	AwayMode    *AwayMode   `json:"away_mode"` // nil unless user is away
+}
+
+// Location returns user's time zone. It returns time.UTC if user's time zone
+// is unknown.
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// AvatarURLs holds URLs of user avatar in different sizes.
+type AvatarURLs struct {
+	S35  string `json:"s35"`
+	S60  string `json:"s60"`
+	S195 string `json:"s195"`
+	S640 string `json:"s640"`
+}
+
+// AwayMode describes user's absence.
+type AwayMode struct {
+	Type     string `json:"type"`      // i.e. "vacation", "parental", "sickleave", "other"
+	DateFrom string `json:"date_from"` // date in YYYY-MM-DD format
+	DateTo   string `json:"date_to"`   // date in YYYY-MM-DD format
 }
 
+// Users returns all users of a given workspace.
 func (c *Client) Users(ctx context.Context, workspaceID uint64) ([]User, error) {
 	if workspaceID == 0 {
 		return nil, errors.New("invalid workspace id")
 	}
 	vals := make(url.Values)
 	vals.Add("id", strconv.FormatUint(workspaceID, 10))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v4/workspace_users/get"+"?"+vals.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
 	var out []User
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
+	if err := c.get(ctx, "v4/workspace_users/get", vals, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
@@ -64,18 +144,8 @@ func (c *Client) Thread(ctx context.Context, threadID uint64) (*Thread, error) {
 	}
 	vals := make(url.Values)
 	vals.Add("id", strconv.FormatUint(threadID, 10))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v3/threads/getone"+"?"+vals.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
 	var out Thread
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
+	if err := c.get(ctx, "v3/threads/getone", vals, &out); err != nil {
 		return nil, err
 	}
 	return &out, nil
@@ -83,18 +153,8 @@ func (c *Client) Thread(ctx context.Context, threadID uint64) (*Thread, error) {
 
 // Workspaces returns all the workspaces user has access to.
 func (c *Client) Workspaces(ctx context.Context) ([]Workspace, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v3/workspaces/get", nil)
-	if err != nil {
-		return nil, err
-	}
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
 	var out []Workspace
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
+	if err := c.get(ctx, "v3/workspaces/get", nil, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
@@ -107,18 +167,8 @@ func (c *Client) Channels(ctx context.Context, workspaceID uint64) ([]Channel, e
 	}
 	vals := make(url.Values)
 	vals.Add("workspace_id", strconv.FormatUint(workspaceID, 10))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v3/channels/get"+"?"+vals.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
 	var out []Channel
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
+	if err := c.get(ctx, "v3/channels/get", vals, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
@@ -138,23 +188,70 @@ type Workspace struct {
 //
 // See https://developer.twist.com/v3/#channels for details.
 type Channel struct {
-	Id       uint64 `json:"id"`
-	Name     string `json:"name"`
-	Archived bool   `json:"archived"`
+	Id            uint64   `json:"id"`
+	WorkspaceID   uint64   `json:"workspace_id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Creator       uint64   `json:"creator"`
+	TsCreated     uint64   `json:"created_ts"`
+	Public        bool     `json:"public"`
+	Archived      bool     `json:"archived"`
+	Color         int      `json:"color"`          // index of a color in Twist palette
+	Members       []uint64 `json:"user_ids"`       // ids of channel members
+	DefaultGroups []uint64 `json:"default_groups"` // ids of groups notified by default
 }
 
+// CreatedAt is a convenience method to convert TsCreated field to time.
+func (ch *Channel) CreatedAt() time.Time { return time.Unix(int64(ch.TsCreated), 0) }
+
 // Thread is a Twist thread. Threads keep team's conversations organized by
 // specific topics. Thread contains comments.
 //
 // See https://developer.twist.com/v3/#threads for details.
 type Thread struct {
-	Id        uint64 `json:"id"`
-	TsPosted  uint64 `json:"posted_ts"`
-	TsUpdated uint64 `json:"last_updated_ts"`
-	Title     string `json:"title"`
-	Text      string `json:"content"`
-	Creator   uint64 `json:"creator"`
-	Archived  bool   `json:"is_archived"`
+	Id           uint64       `json:"id"`
+	ChannelID    uint64       `json:"channel_id"`
+	WorkspaceID  uint64       `json:"workspace_id"`
+	TsPosted     uint64       `json:"posted_ts"`
+	TsUpdated    uint64       `json:"last_updated_ts"`
+	TsEdited     uint64       `json:"last_edited_ts"`
+	Title        string       `json:"title"`
+	Text         string       `json:"content"`
+	Creator      uint64       `json:"creator"`
+	Archived     bool         `json:"is_archived"`
+	CommentCount int          `json:"comment_count"`
+	LastObjIndex int          `json:"last_obj_index"` // OrderIndex of the last comment
+	Pinned       bool         `json:"pinned"`
+	TsPinned     uint64       `json:"pinned_ts"`
+	Starred      bool         `json:"starred"`
+	Unread       bool         `json:"is_unread"`
+	MutedUntil   uint64       `json:"muted_until_ts"` // zero if thread is not muted
+	Recipients   []uint64     `json:"recipients"`
+	Groups       []uint64     `json:"groups"`
+	Participants []uint64     `json:"participants"`
+	Reactions    Reactions    `json:"reactions"`
+	Attachments  []Attachment `json:"attachments"`
+	Actions      []Action     `json:"actions"`
+
+	// Snippet is a short excerpt of the latest thread activity, posted by
+	// SnippetCreator.
+	Snippet        string `json:"snippet"`
+	SnippetCreator uint64 `json:"snippet_creator"`
+
+	// Raw holds JSON object thread was decoded from, including fields that
+	// Thread does not model.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It decodes known fields and
+// keeps a copy of b in Raw field.
+func (t *Thread) UnmarshalJSON(b []byte) error {
+	type thread Thread // prevents recursion
+	if err := json.Unmarshal(b, (*thread)(t)); err != nil {
+		return err
+	}
+	t.Raw = append(t.Raw[:0:0], b...)
+	return nil
 }
 
 // UpdatedAt is a convenience method to convert TsUpdated field to time.
@@ -163,20 +260,110 @@ func (t *Thread) UpdatedAt() time.Time { return time.Unix(int64(t.TsUpdated), 0)
 // PostedAt is a convenience method to convert TsPosted field to time.
 func (t *Thread) PostedAt() time.Time { return time.Unix(int64(t.TsPosted), 0) }
 
+// EditedAt is a convenience method to convert TsEdited field to time. It
+// returns zero time if thread was never edited.
+func (t *Thread) EditedAt() time.Time { return tsToTime(t.TsEdited) }
+
+// PinnedAt is a convenience method to convert TsPinned field to time. It
+// returns zero time if thread is not pinned.
+func (t *Thread) PinnedAt() time.Time { return tsToTime(t.TsPinned) }
+
+// Reactions maps emoji to ids of users who reacted with it.
+type Reactions map[string][]uint64
+
+// Counts returns the number of reactions per emoji.
+func (r Reactions) Counts() map[string]int {
+	out := make(map[string]int, len(r))
+	for emoji, uids := range r {
+		out[emoji] = len(uids)
+	}
+	return out
+}
+
+// Action is a button attached to a thread or a comment.
+type Action struct {
+	Action     string `json:"action"` // i.e. "open_url" or "prefill_message"
+	Type       string `json:"type"`
+	ButtonText string `json:"button_text"`
+	URL        string `json:"url,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
 // Comment is a message posted to a thread.
 //
 // See https://developer.twist.com/v3/#comments for details.
 type Comment struct {
-	Id         uint64 `json:"id"`
-	Text       string `json:"content"`
-	Creator    uint64 `json:"creator"`
-	OrderIndex int    `json:"obj_index"`
-	TsPosted   uint64 `json:"posted_ts"`
+	Id          uint64       `json:"id"`
+	ThreadID    uint64       `json:"thread_id"`
+	ChannelID   uint64       `json:"channel_id"`
+	WorkspaceID uint64       `json:"workspace_id"`
+	Text        string       `json:"content"`
+	Creator     uint64       `json:"creator"`
+	OrderIndex  int          `json:"obj_index"`
+	TsPosted    uint64       `json:"posted_ts"`
+	TsEdited    uint64       `json:"last_edited_ts"`
+	Deleted     bool         `json:"deleted"`
+	Recipients  []uint64     `json:"recipients"`
+	Groups      []uint64     `json:"groups"`
+	Reactions   Reactions    `json:"reactions"`
+	Attachments []Attachment `json:"attachments"`
+	Actions     []Action     `json:"actions"`
+
+	// SystemMessage is non-nil for comments that Twist posts itself to
+	// report thread changes.
+	SystemMessage *SystemMessage `json:"system_message"`
+
+	// Raw holds JSON object comment was decoded from, including fields that
+	// Comment does not model.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It decodes known fields and
+// keeps a copy of b in Raw field.
+func (c *Comment) UnmarshalJSON(b []byte) error {
+	type comment Comment // prevents recursion
+	if err := json.Unmarshal(b, (*comment)(c)); err != nil {
+		return err
+	}
+	c.Raw = append(c.Raw[:0:0], b...)
+	return nil
 }
 
 // PostedAt is a convenience method to convert TsPosted field to time.
 func (c *Comment) PostedAt() time.Time { return time.Unix(int64(c.TsPosted), 0) }
 
+// EditedAt is a convenience method to convert TsEdited field to time. It
+// returns zero time if comment was never edited.
+func (c *Comment) EditedAt() time.Time { return tsToTime(c.TsEdited) }
+
+// SystemMessage describes a thread change reported by a system comment,
+// like thread title update or thread move to another channel.
+type SystemMessage struct {
+	Type          string   `json:"type"` // i.e. "THREAD_TITLE_UPDATED"
+	Initiator     uint64   `json:"initiator"`
+	InitiatorName string   `json:"initiator_name"`
+	ChannelID     uint64   `json:"channel_id"`
+	ChannelName   string   `json:"channel_name"`
+	OldTitle      string   `json:"old_title"`
+	NewTitle      string   `json:"new_title"`
+	UserIDs       []uint64 `json:"user_ids"`
+
+	// Raw holds JSON object system message was decoded from, including
+	// fields that SystemMessage does not model.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It decodes known fields and
+// keeps a copy of b in Raw field.
+func (m *SystemMessage) UnmarshalJSON(b []byte) error {
+	type systemMessage SystemMessage // prevents recursion
+	if err := json.Unmarshal(b, (*systemMessage)(m)); err != nil {
+		return err
+	}
+	m.Raw = append(m.Raw[:0:0], b...)
+	return nil
+}
+
 // ThreadsPaginator returns ThreadsPaginator that fetches all threads of a
 // channel.
 func (c *Client) ThreadsPaginator(channelID uint64) *ThreadsPaginator {
@@ -218,6 +405,9 @@ type ThreadsPaginator struct {
 
 	// only used when fetching updated threads
 	nextSinceTs uint64
+
+	// optional filter_by API argument, only used when fetching all threads
+	filter string
 }
 
 // Next reports whether there's another page to load. It only returns false
@@ -234,7 +424,7 @@ func (cp *ThreadsPaginator) Page(ctx context.Context) ([]Thread, error) {
 	if cp.nextSinceTs != 0 {
 		threads, err = cp.c.getNewChannelThreadsPage(ctx, cp.channelID, cp.nextSinceTs)
 	} else {
-		threads, err = cp.c.getChannelThreadsPage(ctx, cp.channelID, cp.afterID)
+		threads, err = cp.c.getChannelThreadsPage(ctx, cp.channelID, cp.afterID, cp.filter)
 	}
 	if err != nil {
 		return nil, err
@@ -271,28 +461,17 @@ func (c *Client) getNewChannelThreadsPage(ctx context.Context, channelID, sinceT
 	vals.Add("channel_id", strconv.FormatUint(channelID, 10))
 	vals.Add("limit", strconv.Itoa(maxThreadsPerPage))
 	vals.Add("newer_than_ts", strconv.FormatUint(sinceTimestamp, 10))
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.twist.com/api/v3/threads/get", strings.NewReader(vals.Encode()))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
-
 	var out []Thread
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.postQuery(ctx, "v3/threads/get", vals, &out); err != nil {
+		return nil, err
 	}
 	return out, nil
 }
 
 // getChannelThreadsPage returns chunk of threads using precise window based on
-// after_id API argument, suitable to reliably get all channel threads.
-func (c *Client) getChannelThreadsPage(ctx context.Context, channelID, afterID uint64) ([]Thread, error) {
+// after_id API argument, suitable to reliably get all channel threads. If
+// filter is not empty, it is passed as filter_by API argument.
+func (c *Client) getChannelThreadsPage(ctx context.Context, channelID, afterID uint64, filter string) ([]Thread, error) {
 	if channelID == 0 {
 		return nil, errors.New("invalid channel ID")
 	}
@@ -307,22 +486,12 @@ func (c *Client) getChannelThreadsPage(ctx context.Context, channelID, afterID u
 	} else {
 		vals.Add("after_id", strconv.FormatUint(afterID, 10))
 	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.twist.com/api/v3/threads/get", strings.NewReader(vals.Encode()))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
+	if filter != "" {
+		vals.Add("filter_by", filter)
 	}
-	defer body.Close()
-
 	var out []Thread
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.postQuery(ctx, "v3/threads/get", vals, &out); err != nil {
+		return nil, err
 	}
 	if !sort.SliceIsSorted(out, func(i, j int) bool { return out[i].Id < out[j].Id }) {
 		// for _, v := range out {
@@ -427,20 +596,9 @@ func (c *Client) getNewThreadCommentsPage(ctx context.Context, threadID, sinceTi
 	vals.Add("thread_id", strconv.FormatUint(threadID, 10))
 	vals.Add("limit", strconv.Itoa(maxCommentsPerPage))
 	vals.Add("newer_than_ts", strconv.FormatUint(sinceTimestamp, 10))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v3/comments/get"+"?"+vals.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
-
 	var out []Comment
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.get(ctx, "v3/comments/get", vals, &out); err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -463,20 +621,9 @@ func (c *Client) getThreadCommentsPage(ctx context.Context, threadID uint64, fro
 	// API returns results including both {from,to}_obj_index, it calculates
 	// result like [from_obj_index, to_obj_index][:limit]
 	vals.Add("to_obj_index", strconv.Itoa(fromIndex+maxCommentsPerPage-1))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twist.com/api/v3/comments/get"+"?"+vals.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	setAuthHeader(req, c.token)
-	body, err := doRequestWithRetries(req)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
-
 	var out []Comment
-	if err := json.NewDecoder(body).Decode(&out); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.get(ctx, "v3/comments/get", vals, &out); err != nil {
+		return nil, err
 	}
 	if !sort.SliceIsSorted(out, func(i, j int) bool { return out[i].OrderIndex < out[j].OrderIndex }) {
 		return nil, errors.New("API returned comments that are not properly sorted by obj_index")
@@ -495,16 +642,103 @@ func (c *Client) getThreadCommentsPage(ctx context.Context, threadID uint64, fro
 	return out, nil
 }
 
-// doRequestWithRetries calls http.DefaultClient.Do for a given request. It
-// checks that response is 200 OK, and has an "application/json" Content-Type.
-// If response code is 429 Too Many Requests, or one of 5xx, function
-// automatically retries request up to a limited number of attempts. It returns
-// response body on success.
-func doRequestWithRetries(req *http.Request) (io.ReadCloser, error) {
-	attempt := func(req *http.Request) (body io.ReadCloser, tryAgain bool, err error) {
-		resp, err := http.DefaultClient.Do(req)
+// get calls API endpoint (path relative to the base URL, including API
+// version) with GET method, passing vals as query parameters, and decodes
+// JSON response into out.
+func (c *Client) get(ctx context.Context, endpoint string, vals url.Values, out any) error {
+	u := c.baseURL + "/" + endpoint
+	if len(vals) != 0 {
+		u += "?" + vals.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(endpoint, req, true, out)
+}
+
+// post calls API endpoint (path relative to the base URL, including API
+// version) with POST method, passing vals as form-encoded body, and decodes
+// JSON response into out. Endpoint is expected to change data, so failed
+// calls are only retried when server reports they were not processed, see
+// doRequestWithRetries.
+func (c *Client) post(ctx context.Context, endpoint string, vals url.Values, out any) error {
+	return c.doPost(ctx, endpoint, vals, false, out)
+}
+
+// postQuery is like post, but for read-only endpoints that expect POST
+// method, calls to which are safe to retry.
+func (c *Client) postQuery(ctx context.Context, endpoint string, vals url.Values, out any) error {
+	return c.doPost(ctx, endpoint, vals, true, out)
+}
+
+func (c *Client) doPost(ctx context.Context, endpoint string, vals url.Values, safe bool, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+endpoint, strings.NewReader(vals.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerContentType, "application/x-www-form-urlencoded")
+	return c.doJSON(endpoint, req, safe, out)
+}
+
+// doJSON sends request to a given endpoint and decodes its JSON response into
+// out. If out is nil, response is discarded. Safe reports whether request can
+// be repeated without side effects, see doRequestWithRetries.
+func (c *Client) doJSON(endpoint string, req *http.Request, safe bool, out any) error {
+	c.setHeaders(req)
+	body, err := c.doRequestWithRetries(endpoint, req, true, safe)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if out == nil {
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// doRequestWithRetries sends request with Client's http.Client. It checks
+// that response is 200 OK, and, if wantJSON is true, that it has an
+// "application/json" Content-Type. Failed attempts are retried as Client's
+// RetryPolicy decides. It returns response body on success. Responses with
+// unexpected status are reported as *APIError.
+//
+// Safe reports whether request can be repeated without side effects. Requests
+// that are not safe, i.e. ones creating content, may fail after server has
+// already applied them, so they are only retried if server reports that
+// request was not processed, see retryableWrite.
+func (c *Client) doRequestWithRetries(endpoint string, req *http.Request, wantJSON, safe bool) (io.ReadCloser, error) {
+	// attempt returns non-nil resp along with an error if it got a response,
+	// such resp has its body already closed
+	attempt := func(req *http.Request, retries int) (body io.ReadCloser, resp *http.Response, err error) {
+		ctx := req.Context()
+		for _, h := range c.hooks {
+			h.OnRequest(ctx, RequestEvent{Endpoint: endpoint, Method: req.Method, Attempt: retries + 1})
+		}
+		begin := time.Now()
+		resp, err = c.httpClient.Do(req)
 		if err != nil {
-			return nil, false, err
+			err = redactURLError(err)
+			for _, h := range c.hooks {
+				h.OnResponse(ctx, ResponseEvent{Endpoint: endpoint, Method: req.Method, Attempt: retries + 1,
+					Latency: time.Since(begin), Err: err})
+			}
+			return nil, nil, err
+		}
+		if len(c.hooks) != 0 {
+			statusCode := resp.StatusCode
+			resp.Body = &observedBody{ReadCloser: resp.Body, onClose: func(n int64) {
+				for _, h := range c.hooks {
+					// err is a named result, so it holds the final outcome
+					// of this attempt by the time body is closed
+					h.OnResponse(ctx, ResponseEvent{Endpoint: endpoint, Method: req.Method, Attempt: retries + 1,
+						StatusCode: statusCode, Latency: time.Since(begin), BytesRead: n, Err: err})
+				}
+			}}
 		}
 		var defuseBodyClose bool
 		defer func() {
@@ -513,63 +747,107 @@ func doRequestWithRetries(req *http.Request) (io.ReadCloser, error) {
 			}
 			resp.Body.Close()
 		}()
-		switch {
-		case resp.StatusCode == http.StatusOK:
-		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
-			return nil, true, fmt.Errorf("unexpected status: %q", resp.Status)
-		default:
-			return nil, false, fmt.Errorf("unexpected status: %q", resp.Status)
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp, newAPIError(endpoint, resp, retries)
 		}
-		if ct := resp.Header.Get(headerContentType); ct != jsonContentType {
-			return nil, false, fmt.Errorf("unexpected Content-Type: %q", ct)
+		if ct := resp.Header.Get(headerContentType); wantJSON && ct != jsonContentType {
+			return nil, resp, fmt.Errorf("unexpected Content-Type: %q", ct)
 		}
 		defuseBodyClose = true
-		return resp.Body, false, nil
+		return resp.Body, nil, nil
 	}
 
-	var ticker *time.Ticker
-	const maxRetries = 10
-	var lastError error
-
-	for n := 0; n < maxRetries; n++ {
-		if n != 0 && req.Body != nil {
-			if seeker, ok := req.Body.(io.Seeker); ok {
-				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
-					return nil, fmt.Errorf("rewinding request body to 0: %w", err)
-				}
-			} else {
-				return nil, fmt.Errorf("cannot rewind non-nil request body for retry, last error was %w", lastError)
+	var timer *time.Timer
+	for n := 1; ; n++ {
+		if n != 1 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, errors.New("cannot rewind non-nil request body for retry")
 			}
-		}
-		if n != 0 {
-			if ticker == nil {
-				ticker = time.NewTicker(500 * time.Millisecond)
-				defer ticker.Stop()
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
 			}
-			select {
-			case <-ticker.C:
-			case <-req.Context().Done():
-				return nil, req.Context().Err()
+			req.Body = body
+		}
+		if c.limiter != nil {
+			if err := c.limiter.wait(req.Context()); err != nil {
+				return nil, err
 			}
 		}
-		body, tryAgain, err := attempt(req)
-		if err != nil {
-			lastError = err
-			if tryAgain {
-				continue
+		body, resp, err := attempt(req, n-1)
+		if err == nil {
+			return body, nil
+		}
+		if c.retryPolicy == nil || !safe && !retryableWrite(resp) {
+			return nil, err
+		}
+		delay, ok := c.retryPolicy.Retry(n, req, resp, err)
+		if !ok {
+			if n != 1 && attemptsExhausted(c.retryPolicy, n) {
+				return nil, fmt.Errorf("giving up after %d retries, last error was %w", n-1, err)
 			}
 			return nil, err
 		}
-		return body, nil
+		for _, h := range c.hooks {
+			h.OnRetry(req.Context(), RetryEvent{Endpoint: endpoint, Attempt: n, Delay: delay, Err: err})
+		}
+		if timer == nil {
+			timer = time.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// postID calls endpoint that takes a single id argument and returns no data.
+// Kind names the type of object for error messages.
+func (c *Client) postID(ctx context.Context, endpoint, kind string, id uint64) error {
+	if id == 0 {
+		return fmt.Errorf("invalid %s id", kind)
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(id, 10))
+	return c.post(ctx, endpoint, vals, nil)
+}
+
+// addJSON adds JSON-encoded v to vals under a given key, unless v is an
+// empty slice. API expects lists to be passed this way.
+func addJSON[T any](vals url.Values, key string, v []T) error {
+	if len(v) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", key, err)
 	}
-	return nil, fmt.Errorf("giving up after %d retries, last error was %w", maxRetries, lastError)
+	vals.Add(key, string(b))
+	return nil
 }
 
-func setAuthHeader(r *http.Request, token string) {
-	r.Header.Set("Authorization", "Bearer "+token)
-	r.Header.Set("User-Agent", "github.com/artyom/twist")
+// tsToTime converts Unix timestamp to time, mapping zero to zero time.
+func tsToTime(ts uint64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts), 0)
 }
 
+func (c *Client) setHeaders(r *http.Request) {
+	r.Header.Set("Authorization", "Bearer "+c.token)
+	if c.userAgent != "" {
+		r.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
+const defaultBaseURL = "https://api.twist.com/api"
+const defaultUserAgent = "github.com/artyom/twist"
+
 const jsonContentType = "application/json"
 const headerContentType = "Content-Type"
 
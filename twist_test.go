@@ -0,0 +1,997 @@
+package twist_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/twisttest"
+)
+
+func TestThreadsPaginator(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	chID := srv.AddChannel(srv.AddWorkspace(twist.Workspace{Name: "Test"}), twist.Channel{Name: "General"})
+	const total = 250 // spans several pages
+	for i := range total {
+		srv.AddThread(chID, twist.Thread{Title: "Thread " + strconv.Itoa(i), TsUpdated: uint64(1e9 + total - i)})
+	}
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/threads/get", Times: 2, Status: 429, RetryAfter: "0"})
+
+	var got []twist.Thread
+	p := srv.Client().ThreadsPaginator(chID)
+	for p.Next() {
+		threads, err := p.Page(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, threads...)
+	}
+	if len(got) != total {
+		t.Fatalf("got %d threads, want %d", len(got), total)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Id <= got[i-1].Id {
+			t.Fatalf("threads are not sorted by id at position %d", i)
+		}
+	}
+	if n, want := srv.RequestCount("v3/threads/get"), total/100+1+2; n != want {
+		t.Fatalf("server got %d threads/get requests, want %d", n, want)
+	}
+}
+
+var record = flag.Bool("record", false, "record Twist API interactions to testdata")
+
+func TestThreadsPaginator_replay(t *testing.T) {
+	// recording needs TWIST_TOKEN of a user who can access the channel
+	token := os.Getenv("TWIST_TOKEN")
+	rt := twisttest.RecordOrReplay(t, "testdata/threads.json", *record, token)
+	client := twist.New(token, twist.WithHTTPClient(&http.Client{Transport: rt}), twist.WithRetryPolicy(nil))
+	const channelID = 280532
+	var got []twist.Thread
+	for th, err := range client.ThreadsPaginator(channelID).All(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, th)
+	}
+	if *record {
+		return
+	}
+	if len(got) != 105 {
+		t.Fatalf("got %d threads, want 105", len(got))
+	}
+	for i, th := range got {
+		if th.ChannelID != channelID || th.Title == "" || th.PostedAt().IsZero() {
+			t.Fatalf("unexpected thread at position %d: %+v", i, th)
+		}
+		if i > 0 && th.Id <= got[i-1].Id {
+			t.Fatalf("threads are not sorted by id at position %d", i)
+		}
+	}
+}
+
+func TestCommentsPaginator(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	tid := srv.AddThread(1, twist.Thread{Title: "Hello"})
+	const total = 1200
+	for i := range total {
+		srv.AddComment(tid, twist.Comment{Text: "Comment " + strconv.Itoa(i)})
+	}
+	var n int
+	for c, err := range srv.Client().AllComments(context.Background(), tid) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.OrderIndex != n {
+			t.Fatalf("comment %d has OrderIndex %d", n, c.OrderIndex)
+		}
+		n++
+	}
+	if n != total {
+		t.Fatalf("got %d comments, want %d", n, total)
+	}
+}
+
+func TestCommentsPaginator_unsorted(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	tid := srv.AddThread(1, twist.Thread{Title: "Hello"})
+	for range 3 {
+		srv.AddComment(tid, twist.Comment{Text: "Hi"})
+	}
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/comments/get", UnsortedPage: true})
+	_, err := srv.Client().CommentsPaginator(tid).Page(context.Background())
+	if err == nil {
+		t.Fatal("unsorted page was accepted")
+	}
+}
+
+func TestConversationMessagesPaginator(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	convID := srv.AddConversation(1, twist.Conversation{})
+	const total = 1200
+	for i := range total {
+		srv.AddConversationMessage(convID, twist.ConversationMessage{Text: "Message " + strconv.Itoa(i)})
+	}
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/conversation_messages/get", Times: 1, Status: 503})
+	var n int
+	for m, err := range srv.Client().AllConversationMessages(context.Background(), convID) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.OrderIndex != n {
+			t.Fatalf("message %d has OrderIndex %d", n, m.OrderIndex)
+		}
+		n++
+	}
+	if n != total {
+		t.Fatalf("got %d messages, want %d", n, total)
+	}
+	if n, want := srv.RequestCount("v3/conversation_messages/get"), total/500+1+1; n != want {
+		t.Fatalf("server got %d conversation_messages/get requests, want %d", n, want)
+	}
+}
+
+func TestConversationMessagesPaginator_unsorted(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	convID := srv.AddConversation(1, twist.Conversation{})
+	for range 3 {
+		srv.AddConversationMessage(convID, twist.ConversationMessage{Text: "Hi"})
+	}
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/conversation_messages/get", UnsortedPage: true})
+	_, err := srv.Client().ConversationMessagesPaginator(convID).Page(context.Background())
+	if err == nil {
+		t.Fatal("unsorted page was accepted")
+	}
+}
+
+func TestInboxPaginator(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	const total = 250 // spans several pages
+	var unread int
+	for i := range total {
+		if i%3 == 0 {
+			unread++
+		}
+		srv.AddThread(ch, twist.Thread{TsUpdated: uint64(1e9 + i), Unread: i%3 == 0})
+	}
+	archived := srv.AddThread(ch, twist.Thread{TsUpdated: 1e9 + total, Archived: true})
+	other := srv.AddWorkspace(twist.Workspace{Name: "Other"})
+	srv.AddThread(srv.AddChannel(other, twist.Channel{Name: "General"}), twist.Thread{TsUpdated: 1e9})
+
+	client := srv.Client()
+	ctx := context.Background()
+	inbox := func(q twist.InboxQuery) []twist.Thread {
+		t.Helper()
+		var out []twist.Thread
+		for thread, err := range client.InboxPaginator(q).All(ctx) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, thread)
+		}
+		return out
+	}
+
+	got := inbox(twist.InboxQuery{WorkspaceID: ws})
+	if len(got) != total {
+		t.Fatalf("got %d threads, want %d", len(got), total)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].TsUpdated >= got[i-1].TsUpdated {
+			t.Fatalf("threads are not sorted by update time at position %d", i)
+		}
+	}
+	if n, want := srv.RequestCount("v3/inbox/get"), total/100+1; n != want {
+		t.Fatalf("server got %d inbox/get requests, want %d", n, want)
+	}
+	if got := inbox(twist.InboxQuery{WorkspaceID: ws, UnreadOnly: true}); len(got) != unread {
+		t.Fatalf("got %d unread threads, want %d", len(got), unread)
+	}
+	got = inbox(twist.InboxQuery{WorkspaceID: ws, Since: time.Unix(1e9+100, 0), Until: time.Unix(1e9+199, 0)})
+	if len(got) != 100 || got[0].TsUpdated != 1e9+199 || got[99].TsUpdated != 1e9+100 {
+		t.Fatalf("got %d threads within time window, want 100", len(got))
+	}
+
+	if got := inbox(twist.InboxQuery{WorkspaceID: ws, Archived: true}); len(got) != 1 || got[0].Id != archived {
+		t.Fatalf("unexpected archived threads: %+v", got)
+	}
+	if err := client.UnarchiveInbox(ctx, archived); err != nil {
+		t.Fatal(err)
+	}
+	if got := inbox(twist.InboxQuery{WorkspaceID: ws}); len(got) != total+1 || got[0].Id != archived {
+		t.Fatalf("got %d threads after unarchiving, want %d", len(got), total+1)
+	}
+	if err := client.ArchiveInbox(ctx, archived); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ArchiveAllInbox(ctx, ws, time.Unix(1e9+99, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if got := inbox(twist.InboxQuery{WorkspaceID: ws}); len(got) != total-100 {
+		t.Fatalf("got %d threads after archiving, want %d", len(got), total-100)
+	}
+}
+
+func TestInboxPaginator_sameUpdateTime(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	want := make(map[uint64]bool)
+	for i := range 60 {
+		want[srv.AddThread(ch, twist.Thread{TsUpdated: uint64(2e9 - i)})] = true
+	}
+	// threads touched by a bulk action within the same second, spanning
+	// page boundaries
+	for range 90 {
+		want[srv.AddThread(ch, twist.Thread{TsUpdated: 1e9})] = true
+	}
+	for i := range 30 {
+		want[srv.AddThread(ch, twist.Thread{TsUpdated: uint64(1e9 - 1 - i)})] = true
+	}
+
+	got := make(map[uint64]bool)
+	for thread, err := range srv.Client().InboxPaginator(twist.InboxQuery{WorkspaceID: ws}).All(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[thread.Id] {
+			t.Fatalf("thread %d returned twice", thread.Id)
+		}
+		got[thread.Id] = true
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d threads, want %d", len(got), len(want))
+	}
+
+	// more than a page of threads with the same update time
+	for range 110 {
+		srv.AddThread(ch, twist.Thread{TsUpdated: 3e9})
+	}
+	p := srv.Client().InboxPaginator(twist.InboxQuery{WorkspaceID: ws})
+	if _, err := p.Page(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Page(context.Background()); err == nil {
+		t.Fatal("paginator silently skipped threads sharing update time")
+	}
+}
+
+func TestClient_errors(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := srv.Client().Thread(ctx, 123); !twist.IsNotFound(err) {
+		t.Fatalf("got error %v, want not found error", err)
+	}
+	_, err := twist.New("wrong", twist.WithBaseURL(srv.URL)).Workspaces(ctx)
+	var apiErr *twist.APIError
+	if !errors.As(err, &apiErr) || !twist.IsUnauthorized(err) {
+		t.Fatalf("got error %v, want unauthorized APIError", err)
+	}
+	if apiErr.Endpoint != "v3/workspaces/get" || apiErr.Message == "" {
+		t.Fatalf("APIError lacks details: %+v", apiErr)
+	}
+
+	srv.InjectFault(twisttest.Fault{Status: 503})
+	var retries int
+	client := srv.Client(twist.WithRetryHook(func(context.Context, twist.RetryEvent) { retries++ }))
+	if _, err := client.Workspaces(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != 503 {
+		t.Fatalf("got error %v, want APIError with 503 status", err)
+	}
+	if apiErr.Retries != 9 || retries != 9 {
+		t.Fatalf("got %d retries reported by error and %d by hook, want 9", apiErr.Retries, retries)
+	}
+}
+
+func TestClient_AddComment_noDuplicates(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	tid := srv.AddThread(1, twist.Thread{Title: "Hello"})
+	client := srv.Client()
+	ctx := context.Background()
+
+	// server fails after saving comment: client must not post it again
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/comments/add", Times: 1, Status: 503, Committed: true})
+	if _, err := client.AddComment(ctx, twist.NewComment{ThreadID: tid, Content: "first"}); err == nil {
+		t.Fatal("AddComment succeeded despite injected fault")
+	}
+	if n := len(srv.Comments(tid)); n != 1 {
+		t.Fatalf("got %d comments after failed AddComment, want 1", n)
+	}
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/comments/add", Times: 1, Status: 502, Committed: true})
+	if _, err := client.AddComment(ctx, twist.NewComment{ThreadID: tid, Content: "second"}); err == nil {
+		t.Fatal("AddComment succeeded despite injected fault")
+	}
+	if n := srv.RequestCount("v3/comments/add"); n != 2 {
+		t.Fatalf("server got %d comments/add requests, want 2", n)
+	}
+
+	// requests that server reports as not processed are retried
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/comments/add", Times: 1, Status: 429})
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/comments/add", Times: 1, Status: 503, RetryAfter: "0"})
+	if _, err := client.AddComment(ctx, twist.NewComment{ThreadID: tid, Content: "third"}); err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, c := range srv.Comments(tid) {
+		texts = append(texts, c.Text)
+	}
+	if want := []string{"first", "second", "third"}; !slices.Equal(texts, want) {
+		t.Fatalf("got comments %q, want %q", texts, want)
+	}
+
+	// read-only POST endpoints are still retried on 5xx
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/threads/get", Times: 2, Status: 502})
+	if _, err := client.ThreadsPaginator(1).Page(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_badContentType(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/workspaces/get", BadContentType: true})
+	if _, err := srv.Client().Workspaces(context.Background()); err == nil {
+		t.Fatal("response with bad Content-Type was accepted")
+	}
+}
+
+func TestThread_UnmarshalJSON(t *testing.T) {
+	const data = `{"id":1,"channel_id":2,"title":"Hello","reactions":{"👍":[3,4]},"new_field":true}`
+	var thread twist.Thread
+	if err := json.Unmarshal([]byte(data), &thread); err != nil {
+		t.Fatal(err)
+	}
+	if thread.ChannelID != 2 || len(thread.Reactions["👍"]) != 2 {
+		t.Fatalf("unexpected decoded thread: %+v", thread)
+	}
+	if string(thread.Raw) != data {
+		t.Fatalf("got raw JSON %s, want %s", thread.Raw, data)
+	}
+}
+
+func TestClient_DownloadAttachment(t *testing.T) {
+	var gotAuth string
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		io.WriteString(w, "file contents")
+	}))
+	defer files.Close()
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+
+	rc, err := srv.Client().DownloadAttachment(context.Background(), twist.Attachment{URL: files.URL + "/file.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "file contents" {
+		t.Fatalf("got %q, want %q", b, "file contents")
+	}
+	if gotAuth != "" {
+		t.Fatal("token was sent to a third-party host")
+	}
+}
+
+func TestClient_UploadAttachment(t *testing.T) {
+	type upload struct{ id, filename, contentType, body string }
+	var got []upload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/attachments/upload" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(r.MultipartForm.File) != 1 || len(r.MultipartForm.File["file_name"]) != 1 {
+			http.Error(w, "want a single file in file_name part", http.StatusBadRequest)
+			return
+		}
+		for _, fh := range r.MultipartForm.File["file_name"] {
+			f, err := fh.Open()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			b, _ := io.ReadAll(f)
+			f.Close()
+			got = append(got, upload{r.FormValue("attachment_id"), fh.Filename, fh.Header.Get("Content-Type"), string(b)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(twist.Attachment{Id: r.FormValue("attachment_id"), UploadState: "uploaded"})
+	}))
+	defer srv.Close()
+	client := twist.New("token", twist.WithBaseURL(srv.URL), twist.WithRetryPolicy(nil))
+	ctx := context.Background()
+
+	a, err := client.UploadAttachment(ctx, strings.NewReader("%PDF-1.7"), "report.pdf", "application/pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UploadAttachment(ctx, strings.NewReader("\x00\x01"), "dump.bin", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("server got %d uploads, want 2", len(got))
+	}
+	uuidRe := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, u := range got {
+		if !uuidRe.MatchString(u.id) {
+			t.Errorf("attachment_id %q is not a random UUID", u.id)
+		}
+	}
+	if got[0].id == got[1].id {
+		t.Error("uploads share attachment_id")
+	}
+	if a.Id != got[0].id {
+		t.Errorf("got attachment id %q, want %q", a.Id, got[0].id)
+	}
+	for i, want := range []upload{
+		{filename: "report.pdf", contentType: "application/pdf", body: "%PDF-1.7"},
+		{filename: "dump.bin", contentType: "application/octet-stream", body: "\x00\x01"},
+	} {
+		if want.id = got[i].id; got[i] != want {
+			t.Errorf("upload %d: got %+v, want %+v", i, got[i], want)
+		}
+	}
+	if _, err := client.UploadAttachment(ctx, strings.NewReader(""), "", ""); err == nil {
+		t.Error("upload with empty file name succeeded")
+	}
+}
+
+func TestClient_PinnedThreads(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	pinned := srv.AddThread(1, twist.Thread{Title: "Runbook", Pinned: true})
+	srv.AddThread(1, twist.Thread{Title: "Chatter"})
+	srv.AddThread(2, twist.Thread{Title: "Other channel runbook", Pinned: true})
+	// results must be correct even if API doesn't apply the filter
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/threads/get", IgnoreFilter: true})
+	threads, err := srv.Client().PinnedThreads(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 1 || threads[0].Id != pinned {
+		t.Fatalf("got unexpected pinned threads: %+v", threads)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || reqs[0].Params.Get("filter_by") != "is_pinned" {
+		t.Fatalf("unexpected requests: %+v", reqs)
+	}
+}
+
+func TestClient_StarredThreads(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	var want []uint64
+	for i := range 3 {
+		ch := srv.AddChannel(ws, twist.Channel{Name: "Channel " + strconv.Itoa(i)})
+		want = append(want, srv.AddThread(ch, twist.Thread{Title: "Starred", Starred: true}))
+		srv.AddThread(ch, twist.Thread{Title: "Chatter"})
+	}
+	other := srv.AddWorkspace(twist.Workspace{Name: "Other"})
+	srv.AddThread(srv.AddChannel(other, twist.Channel{Name: "General"}), twist.Thread{Starred: true})
+
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/threads/get", IgnoreFilter: true})
+	threads, err := srv.Client().StarredThreads(context.Background(), ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for _, th := range threads {
+		got = append(got, th.Id)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("got starred threads %v, want %v", got, want)
+	}
+}
+
+func TestIntegration_Post(t *testing.T) {
+	var calls int
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			// posts are only retried if server asks so
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("integration request has Authorization header")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+	in, err := twist.NewIntegration(srv.URL+"/integration_incoming/post_data?install_id=1&install_token=secret",
+		twist.WithRetryPolicy(&twist.BackoffPolicy{MaxAttempts: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Post(context.Background(), twist.IntegrationPost{Title: "Alert", Content: "Disk is full"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || got["title"] != "Alert" || got["content"] != "Disk is full" {
+		t.Fatalf("got %d calls, last one with %v", calls, got)
+	}
+}
+
+func TestIntegration_Post_droppedConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+	h := new(recordingHook)
+	in, err := twist.NewIntegration(srv.URL+"/integration_incoming/post_data?install_id=1&install_token=s3cr3t",
+		twist.WithHook(h), twist.WithRetryPolicy(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = in.Post(context.Background(), twist.IntegrationPost{Content: "Disk is full"})
+	if err == nil {
+		t.Fatal("Post succeeded over dropped connection")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Fatalf("error leaks install token: %v", err)
+	}
+	if !strings.Contains(err.Error(), "/integration_incoming/post_data") {
+		t.Fatalf("error lacks request URL: %v", err)
+	}
+	if len(h.responses) != 1 || h.responses[0].Err == nil || strings.Contains(h.responses[0].Err.Error(), "s3cr3t") {
+		t.Fatalf("unexpected response events: %+v", h.responses)
+	}
+	if _, err := twist.NewIntegration("ftp://example.com/?install_token=s3cr3t"); err == nil || strings.Contains(err.Error(), "s3cr3t") {
+		t.Fatalf("got error %v, want one without install token", err)
+	}
+}
+
+func TestSearchPaginator(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	other := srv.AddChannel(ws, twist.Channel{Name: "Other"})
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ts := func(days int) uint64 { return uint64(day.AddDate(0, 0, days).Unix()) }
+	tid := srv.AddThread(ch, twist.Thread{Title: "INC-42", Creator: 1, TsPosted: ts(-5)})
+	var want []uint64
+	for i := range 300 {
+		c := twist.Comment{Text: "about INC-42", Creator: 1, TsPosted: ts(i % 3)}
+		switch i % 5 {
+		case 1:
+			c.Creator = 2 // filtered by author
+		case 2:
+			c.Text = "unrelated" // doesn't match
+		case 3:
+			c.TsPosted = ts(3) // after date_to
+		}
+		if id := srv.AddComment(tid, c); c.Creator == 1 && c.Text != "unrelated" && c.TsPosted != ts(3) {
+			want = append(want, id)
+		}
+	}
+	srv.AddComment(srv.AddThread(other, twist.Thread{Title: "Other"}),
+		twist.Comment{Text: "INC-42 elsewhere", Creator: 1, TsPosted: ts(0)})
+
+	p := srv.Client().SearchPaginator(twist.SearchQuery{
+		WorkspaceID: ws,
+		Query:       "inc-42",
+		ChannelIDs:  []uint64{ch},
+		AuthorIDs:   []uint64{1},
+		Since:       day,
+		Until:       day.AddDate(0, 0, 2),
+		Type:        twist.SearchComment,
+	})
+	var got []uint64
+	for r, err := range p.All(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Type != twist.SearchComment || r.ThreadID != tid || r.ChannelID != ch {
+			t.Fatalf("unexpected search result: %+v", r)
+		}
+		got = append(got, r.CommentID)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %d results, want %d:\ngot:  %v\nwant: %v", len(got), len(want), got, want)
+	}
+	if p.Next() {
+		t.Fatal("paginator has more pages after all results are read")
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	params := reqs[0].Params
+	for key, want := range map[string]string{
+		"workspace_id": strconv.FormatUint(ws, 10),
+		"query":        "inc-42",
+		"channel_ids":  "[" + strconv.FormatUint(ch, 10) + "]",
+		"author_ids":   "[1]",
+		"date_from":    "2024-05-01",
+		"date_to":      "2024-05-03",
+		"type":         "comment",
+		"cursor_mark":  "",
+	} {
+		if got := params.Get(key); got != want {
+			t.Errorf("first request %s=%q, want %q", key, got, want)
+		}
+	}
+	for _, key := range []string{"conversation_ids", "title_only"} {
+		if params.Has(key) {
+			t.Errorf("first request has unexpected %s=%q", key, params.Get(key))
+		}
+	}
+	if cursor := reqs[1].Params.Get("cursor_mark"); cursor == "" {
+		t.Error("second request has no cursor_mark")
+	}
+}
+
+func TestClient_Search_titleOnly(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	tid := srv.AddThread(ch, twist.Thread{Title: "Postmortem INC-42"})
+	srv.AddThread(ch, twist.Thread{Title: "Chatter", Text: "see INC-42"})
+	srv.AddComment(tid, twist.Comment{Text: "INC-42 is resolved"})
+
+	res, err := srv.Client().Search(context.Background(), twist.SearchQuery{WorkspaceID: ws, Query: "INC-42", TitleOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Type != twist.SearchThread || res[0].ThreadID != tid {
+		t.Fatalf("unexpected search results: %+v", res)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || reqs[0].Params.Get("title_only") != "true" {
+		t.Fatalf("unexpected requests: %+v", reqs)
+	}
+}
+
+func TestClient_channels(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	client := srv.Client()
+	ctx := context.Background()
+	lastParams := func() url.Values {
+		reqs := srv.Requests()
+		return reqs[len(reqs)-1].Params
+	}
+	checkParams := func(params url.Values, want map[string]string) {
+		t.Helper()
+		for key, want := range want {
+			if got, ok := params[key]; want == "" && ok {
+				t.Errorf("%s=%q is sent, want it omitted", key, got)
+			} else if want != "" && params.Get(key) != want {
+				t.Errorf("%s=%q, want %q", key, params.Get(key), want)
+			}
+		}
+	}
+
+	ch, err := client.AddChannel(ctx, twist.NewChannel{
+		WorkspaceID:   ws,
+		Name:          "Ops",
+		Color:         2,
+		Public:        true,
+		Members:       []uint64{3, 1},
+		DefaultGroups: []uint64{7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParams(lastParams(), map[string]string{
+		"workspace_id":   strconv.FormatUint(ws, 10),
+		"name":           "Ops",
+		"description":    "",
+		"color":          "2",
+		"public":         "true",
+		"user_ids":       "[3,1]",
+		"default_groups": "[7]",
+	})
+	if ch.WorkspaceID != ws || ch.Color != 2 || !ch.Public || !slices.Equal(ch.Members, []uint64{3, 1}) {
+		t.Fatalf("unexpected new channel: %+v", ch)
+	}
+
+	// nil Color and Public are not sent and keep their values
+	if ch, err = client.UpdateChannel(ctx, twist.ChannelUpdate{Id: ch.Id, Description: "On-call"}); err != nil {
+		t.Fatal(err)
+	}
+	checkParams(lastParams(), map[string]string{
+		"description":    "On-call",
+		"name":           "",
+		"color":          "",
+		"public":         "",
+		"default_groups": "",
+	})
+	if ch.Name != "Ops" || ch.Description != "On-call" || ch.Color != 2 || !ch.Public {
+		t.Fatalf("unexpected updated channel: %+v", ch)
+	}
+	// non-nil Color and Public are sent even if they're zero
+	color, public := 0, false
+	if ch, err = client.UpdateChannel(ctx, twist.ChannelUpdate{Id: ch.Id, Color: &color, Public: &public}); err != nil {
+		t.Fatal(err)
+	}
+	checkParams(lastParams(), map[string]string{"color": "0", "public": "false"})
+	if ch.Color != 0 || ch.Public {
+		t.Fatalf("unexpected updated channel: %+v", ch)
+	}
+
+	if err := client.AddChannelMembers(ctx, ch.Id, 5, 6); err != nil {
+		t.Fatal(err)
+	}
+	checkParams(lastParams(), map[string]string{"id": strconv.FormatUint(ch.Id, 10), "user_ids": "[5,6]"})
+	if err := client.RemoveChannelMembers(ctx, ch.Id, 3); err != nil {
+		t.Fatal(err)
+	}
+	checkParams(lastParams(), map[string]string{"user_ids": "[3]"})
+	n := len(srv.Requests())
+	if err := client.AddChannelMembers(ctx, ch.Id); err == nil {
+		t.Fatal("AddChannelMembers with no users succeeded")
+	}
+	if len(srv.Requests()) != n {
+		t.Fatal("AddChannelMembers with no users sent a request")
+	}
+	members, err := client.ChannelMembers(ctx, ch.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint64{1, 5, 6}; !slices.Equal(members, want) {
+		t.Fatalf("got channel members %v, want %v", members, want)
+	}
+	if _, err := client.ChannelMembers(ctx, ch.Id+100); !twist.IsNotFound(err) {
+		t.Fatalf("got error %v for unknown channel, want not found error", err)
+	}
+}
+
+func TestClient_groups(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	srv.AddGroup(srv.AddWorkspace(twist.Workspace{Name: "Other"}), twist.Group{Name: "Elsewhere"})
+	client := srv.Client()
+	ctx := context.Background()
+	lastParams := func() url.Values {
+		reqs := srv.Requests()
+		return reqs[len(reqs)-1].Params
+	}
+
+	g, err := client.AddGroup(ctx, twist.NewGroup{WorkspaceID: ws, Name: "Backend", Members: []uint64{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := lastParams(); p.Get("user_ids") != "[1,2]" || p.Get("name") != "Backend" || p.Has("description") {
+		t.Fatalf("unexpected AddGroup parameters: %v", p)
+	}
+	if g.WorkspaceID != ws || g.Name != "Backend" || !slices.Equal(g.Members, []uint64{1, 2}) {
+		t.Fatalf("unexpected new group: %+v", g)
+	}
+
+	if g, err = client.UpdateGroup(ctx, twist.GroupUpdate{Id: g.Id, Description: "API and storage"}); err != nil {
+		t.Fatal(err)
+	}
+	if p := lastParams(); p.Has("name") || p.Get("description") != "API and storage" {
+		t.Fatalf("unexpected UpdateGroup parameters: %v", p)
+	}
+	if g.Name != "Backend" || g.Description != "API and storage" {
+		t.Fatalf("unexpected updated group: %+v", g)
+	}
+
+	if err := client.AddUserToGroup(ctx, g.Id, 3); err != nil {
+		t.Fatal(err)
+	}
+	if p := lastParams(); p.Get("id") != strconv.FormatUint(g.Id, 10) || p.Get("user_id") != "3" {
+		t.Fatalf("unexpected AddUserToGroup parameters: %v", p)
+	}
+	if err := client.RemoveUserFromGroup(ctx, g.Id, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddUserToGroup(ctx, g.Id, 0); err == nil {
+		t.Fatal("AddUserToGroup with zero user id succeeded")
+	}
+
+	groups, err := client.Groups(ctx, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Id != g.Id || !slices.Equal(groups[0].Members, []uint64{2, 3}) {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if err := client.RemoveGroup(ctx, g.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Group(ctx, g.Id); !twist.IsNotFound(err) {
+		t.Fatalf("got error %v for removed group, want not found error", err)
+	}
+}
+
+func TestClient_reactions(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	srv.UserID = 42
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	tid := srv.AddThread(srv.AddChannel(ws, twist.Channel{Name: "General"}), twist.Thread{Title: "Hello"})
+	cid := srv.AddComment(tid, twist.Comment{Text: "Hi", Reactions: twist.Reactions{"👍": {7}}})
+	mid := srv.AddConversationMessage(srv.AddConversation(ws, twist.Conversation{}), twist.ConversationMessage{Text: "Hi"})
+	client := srv.Client()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		target twist.ReactionTarget
+		param  string
+		id     uint64
+		want   []uint64 // users who reacted with 👍
+	}{
+		{twist.ThreadTarget(tid), "thread_id", tid, []uint64{42}},
+		{twist.CommentTarget(cid), "comment_id", cid, []uint64{7, 42}},
+		{twist.MessageTarget(mid), "message_id", mid, []uint64{42}},
+	} {
+		if err := client.AddReaction(ctx, tc.target, "👍"); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.AddReaction(ctx, tc.target, "🎉"); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.RemoveReaction(ctx, tc.target, "🎉"); err != nil {
+			t.Fatal(err)
+		}
+		reactions, err := client.Reactions(ctx, tc.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reactions) != 1 || !slices.Equal(reactions["👍"], tc.want) {
+			t.Errorf("%s: got reactions %v, want 👍 by %v", tc.param, reactions, tc.want)
+		}
+		reqs := srv.Requests()
+		for _, r := range reqs[len(reqs)-4:] {
+			for _, param := range []string{"thread_id", "comment_id", "message_id"} {
+				if want := strconv.FormatUint(tc.id, 10); param == tc.param && r.Params.Get(param) != want ||
+					param != tc.param && r.Params.Has(param) {
+					t.Errorf("%s: unexpected %s parameters: %v", tc.param, r.Endpoint, r.Params)
+				}
+			}
+		}
+		if r := reqs[len(reqs)-2]; r.Endpoint != "v3/reactions/remove" || r.Params.Get("reaction") != "🎉" {
+			t.Errorf("%s: unexpected request: %+v", tc.param, r)
+		}
+	}
+
+	n := len(srv.Requests())
+	if err := client.AddReaction(ctx, twist.CommentTarget(cid), ""); err == nil {
+		t.Error("AddReaction with empty emoji succeeded")
+	}
+	for _, target := range []twist.ReactionTarget{{}, twist.ThreadTarget(0), twist.MessageTarget(0)} {
+		if err := client.AddReaction(ctx, target, "👍"); err == nil {
+			t.Errorf("AddReaction to %+v succeeded", target)
+		}
+		if _, err := client.Reactions(ctx, target); err == nil {
+			t.Errorf("Reactions of %+v succeeded", target)
+		}
+	}
+	if len(srv.Requests()) != n {
+		t.Error("invalid reaction calls sent requests")
+	}
+}
+
+func TestClient_threads(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	srv.UserID = 42
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	client := srv.Client()
+	ctx := context.Background()
+	lastParams := func() url.Values {
+		reqs := srv.Requests()
+		return reqs[len(reqs)-1].Params
+	}
+
+	att := twist.Attachment{Id: "0b7a3d52-3c1c-4c4e-9d0b-3d7f2b8f2a11", Title: "report.pdf", URLType: "file"}
+	th, err := client.AddThread(ctx, twist.NewThread{
+		ChannelID:         ch,
+		Title:             "Weekly report",
+		Content:           "See attached",
+		Recipients:        []uint64{1, 2},
+		Groups:            []uint64{9},
+		Attachments:       []twist.Attachment{att},
+		SendAsIntegration: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := lastParams()
+	for key, want := range map[string]string{
+		"channel_id":          strconv.FormatUint(ch, 10),
+		"title":               "Weekly report",
+		"content":             "See attached",
+		"recipients":          "[1,2]",
+		"groups":              "[9]",
+		"attachments":         `[{"attachment_id":"0b7a3d52-3c1c-4c4e-9d0b-3d7f2b8f2a11","title":"report.pdf","url_type":"file"}]`,
+		"send_as_integration": "true",
+	} {
+		if got := p.Get(key); got != want {
+			t.Errorf("AddThread sent %s=%q, want %q", key, got, want)
+		}
+	}
+	if th.WorkspaceID != ws || th.Creator != 42 || !slices.Equal(th.Recipients, []uint64{1, 2}) ||
+		len(th.Attachments) != 1 || th.Attachments[0] != att {
+		t.Fatalf("unexpected new thread: %+v", th)
+	}
+	if _, err := client.AddThread(ctx, twist.NewThread{ChannelID: ch, Title: "Plain"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"recipients", "groups", "attachments", "send_as_integration"} {
+		if p := lastParams(); p.Has(key) {
+			t.Errorf("AddThread sent %s=%q for zero field", key, p.Get(key))
+		}
+	}
+
+	// zero fields are not sent and keep their values
+	if th, err = client.UpdateThread(ctx, twist.ThreadUpdate{Id: th.Id, Title: "Weekly report #12"}); err != nil {
+		t.Fatal(err)
+	}
+	if p := lastParams(); len(p) != 2 || p.Get("id") != strconv.FormatUint(th.Id, 10) || p.Get("title") != "Weekly report #12" {
+		t.Fatalf("unexpected UpdateThread parameters: %v", p)
+	}
+	if th.Title != "Weekly report #12" || th.Text != "See attached" || len(th.Attachments) != 1 {
+		t.Fatalf("unexpected updated thread: %+v", th)
+	}
+	if th, err = client.UpdateThread(ctx, twist.ThreadUpdate{Id: th.Id, Content: "Updated", Attachments: []twist.Attachment{}}); err != nil {
+		t.Fatal(err)
+	}
+	// empty attachments list is a zero value too, it can't remove attachments
+	if p := lastParams(); p.Has("title") || p.Has("attachments") || p.Get("content") != "Updated" {
+		t.Fatalf("unexpected UpdateThread parameters: %v", p)
+	}
+	if len(th.Attachments) != 1 {
+		t.Fatalf("unexpected updated thread attachments: %+v", th.Attachments)
+	}
+
+	if err := client.RemoveThread(ctx, th.Id); err != nil {
+		t.Fatal(err)
+	}
+	if p := lastParams(); len(p) != 1 || p.Get("id") != strconv.FormatUint(th.Id, 10) {
+		t.Fatalf("unexpected RemoveThread parameters: %v", p)
+	}
+	if _, err := client.Thread(ctx, th.Id); !twist.IsNotFound(err) {
+		t.Fatalf("got error %v for removed thread, want not found error", err)
+	}
+	if err := client.RemoveThread(ctx, 0); err == nil {
+		t.Fatal("RemoveThread with zero id succeeded")
+	}
+}
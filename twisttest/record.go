@@ -0,0 +1,292 @@
+package twisttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Interaction is a recorded request and response pair.
+type Interaction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`               // request path with query, without scheme and host
+	RequestBody string `json:"request,omitempty"` // form-encoded request body, if any
+
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	RetryAfter  string `json:"retry_after,omitempty"`
+	Body        string `json:"response"`
+}
+
+// Recorder is an http.RoundTripper that passes requests to an underlying
+// transport and records sanitized request and response pairs, which can be
+// saved to a file and later served by Replayer.
+//
+// Recorder never records request headers, so authentication token does not
+// end up in recordings. It also replaces e-mail addresses with
+// "redacted@example.com", and any of Secrets with "REDACTED", in both
+// requests and responses. Query and form-encoded request parameters are
+// decoded before redaction, so escaped values are redacted too. To replay
+// requests redacted this way, Replayer must be given the same Secrets.
+type Recorder struct {
+	// Transport is used to send requests. If nil, http.DefaultTransport is
+	// used.
+	Transport http.RoundTripper
+
+	// Secrets are strings to redact from recordings.
+	Secrets []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, Interaction{
+		Method:      req.Method,
+		URL:         redactor(r.Secrets).uri(req.URL),
+		RequestBody: redactor(r.Secrets).body(req.Header, reqBody),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		RetryAfter:  resp.Header.Get("Retry-After"),
+		Body:        redactor(r.Secrets).redact(string(respBody)),
+	})
+	return resp, nil
+}
+
+var emailRe = regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)
+
+// redactor removes secrets and e-mail addresses from recorded data.
+type redactor []string
+
+func (rd redactor) redact(s string) string {
+	for _, secret := range rd {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "REDACTED")
+		}
+	}
+	return emailRe.ReplaceAllString(s, "redacted@example.com")
+}
+
+// uri returns redacted request path with query. Query parameters are decoded
+// before redaction and then encoded back.
+func (rd redactor) uri(u *url.URL) string {
+	out := rd.redact(u.EscapedPath())
+	if u.RawQuery != "" {
+		out += "?" + rd.query(u.RawQuery)
+	}
+	return out
+}
+
+// body returns redacted request body. Form-encoded bodies are decoded before
+// redaction and then encoded back.
+func (rd redactor) body(header http.Header, body []byte) string {
+	if mediatype, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediatype == "application/x-www-form-urlencoded" {
+		return rd.query(string(body))
+	}
+	return rd.redact(string(body))
+}
+
+func (rd redactor) query(s string) string {
+	vals, err := url.ParseQuery(s)
+	if err != nil {
+		return rd.redact(s)
+	}
+	out := make(url.Values, len(vals))
+	for k, vv := range vals {
+		k = rd.redact(k)
+		for _, v := range vv {
+			out[k] = append(out[k], rd.redact(v))
+		}
+	}
+	return out.Encode()
+}
+
+// Interactions returns all interactions recorded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.interactions)
+}
+
+// Save writes recorded interactions to a file as JSON.
+func (r *Recorder) Save(name string) error {
+	b, err := json.MarshalIndent(r.Interactions(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(b, '\n'), 0644)
+}
+
+// Replayer is an http.RoundTripper that serves responses recorded by
+// Recorder. Each recorded interaction is served once: request is matched to
+// the first unused interaction with the same method, path, query and body.
+// Requests not matching any of unused interactions fail.
+//
+// Before matching, requests are redacted the same way Recorder does, so
+// requests carrying e-mail addresses or Secrets match their recordings.
+type Replayer struct {
+	// Secrets are strings redacted from requests, they should be the same
+	// as Recorder.Secrets used to make recording.
+	Secrets []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer returns Replayer serving given interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	return &Replayer{
+		interactions: slices.Clone(interactions),
+		used:         make([]bool, len(interactions)),
+	}
+}
+
+// LoadReplayer returns Replayer serving interactions from a file written by
+// Recorder.Save.
+func LoadReplayer(name string) (*Replayer, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(b, &interactions); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return NewReplayer(interactions), nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	uri := redactor(r.Secrets).uri(req.URL)
+	body := redactor(r.Secrets).body(req.Header, reqBody)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || in.Method != req.Method || in.URL != uri || in.RequestBody != body {
+			continue
+		}
+		r.used[i] = true
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			StatusCode:    in.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          io.NopCloser(strings.NewReader(in.Body)),
+			ContentLength: int64(len(in.Body)),
+			Request:       req,
+		}
+		if in.ContentType != "" {
+			resp.Header.Set("Content-Type", in.ContentType)
+		}
+		if in.RetryAfter != "" {
+			resp.Header.Set("Retry-After", in.RetryAfter)
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("twisttest: unexpected request %s %s", req.Method, uri)
+}
+
+// Unused returns the number of interactions not served yet.
+func (r *Replayer) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// RecordOrReplay returns http.RoundTripper to use in tests with
+// twist.WithHTTPClient. If record is true, it returns Recorder that calls
+// real API and saves its recording to a file once test completes, redacting
+// secrets. Otherwise it returns Replayer serving interactions from a file,
+// failing the test if not all of them were used.
+//
+// Typical usage:
+//
+//	var record = flag.Bool("record", false, "record Twist API interactions")
+//
+//	func TestSomething(t *testing.T) {
+//		token := os.Getenv("TWIST_TOKEN")
+//		rt := twisttest.RecordOrReplay(t, "testdata/something.json", *record, token)
+//		client := twist.New(token, twist.WithHTTPClient(&http.Client{Transport: rt}))
+//		...
+//	}
+func RecordOrReplay(t testing.TB, name string, record bool, secrets ...string) http.RoundTripper {
+	t.Helper()
+	if record {
+		rec := &Recorder{Secrets: secrets}
+		t.Cleanup(func() {
+			if err := rec.Save(name); err != nil {
+				t.Errorf("saving recording: %v", err)
+			}
+		})
+		return rec
+	}
+	rep, err := LoadReplayer(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("no recording at %s, run test in record mode first", name)
+		}
+		t.Fatal(err)
+	}
+	rep.Secrets = secrets
+	t.Cleanup(func() {
+		if n := rep.Unused(); n != 0 && !t.Failed() {
+			t.Errorf("%d recorded interactions were not replayed", n)
+		}
+	})
+	return rep
+}
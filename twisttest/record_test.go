@@ -0,0 +1,109 @@
+package twisttest_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/twisttest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := twisttest.NewServer("secret-token")
+	defer srv.Close()
+	wsID := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	srv.AddChannel(wsID, twist.Channel{Name: "General, see john@example.org"})
+	srv.AddUser(wsID, twist.User{Name: "John", Email: "john@example.org"})
+
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "recording.json")
+	rec := &twisttest.Recorder{Transport: http.DefaultTransport, Secrets: []string{"General"}}
+	client := srv.Client(twist.WithHTTPClient(&http.Client{Transport: rec}))
+	if _, err := client.Channels(ctx, wsID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UserByEmail(ctx, wsID, "john@example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	if b, err := os.ReadFile(name); err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(b, []byte("john")) || bytes.Contains(b, []byte("General")) {
+		t.Fatalf("recording has unredacted data:\n%s", b)
+	}
+
+	rep, err := twisttest.LoadReplayer(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep.Secrets = rec.Secrets
+	client = twist.New("", twist.WithBaseURL("https://api.example.com"),
+		twist.WithHTTPClient(&http.Client{Transport: rep}), twist.WithRetryPolicy(nil))
+	channels, err := client.Channels(ctx, wsID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0].Name != "REDACTED, see redacted@example.com" {
+		t.Fatalf("unexpected replayed channels: %+v", channels)
+	}
+	if u, err := client.UserByEmail(ctx, wsID, "john@example.org"); err != nil || u.Email != "redacted@example.com" {
+		t.Fatalf("replaying request with redacted e-mail: %+v, %v", u, err)
+	}
+	if rep.Unused() != 0 {
+		t.Fatalf("%d interactions were not replayed", rep.Unused())
+	}
+	if _, err := client.Channels(ctx, wsID); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Fatalf("got error %v, want unexpected request error", err)
+	}
+}
+
+func TestRecorder_escapedSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	secrets := []string{"top secret"}
+	send := func(rt http.RoundTripper) error {
+		form := url.Values{"note": {"top secret"}, "email": {"jane.doe+test@example.org"}}
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v3/search?"+form.Encode(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := (&http.Client{Transport: rt}).Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	rec := &twisttest.Recorder{Secrets: secrets}
+	if err := send(rec); err != nil {
+		t.Fatal(err)
+	}
+	in := rec.Interactions()[0]
+	for _, s := range []string{in.URL, in.RequestBody} {
+		if strings.Contains(s, "secret") || strings.Contains(s, "jane") {
+			t.Fatalf("recorded request is not redacted: %s", s)
+		}
+	}
+
+	rep := twisttest.NewReplayer(rec.Interactions())
+	rep.Secrets = secrets
+	if err := send(rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Unused() != 0 {
+		t.Fatal("redacted request was not replayed")
+	}
+}
@@ -0,0 +1,1314 @@
+// Package twisttest provides an in-memory fake of Twist API for tests.
+//
+// Server implements a subset of Twist API endpoints that twist.Client uses,
+// serving data seeded with its Add* methods. It can inject faults into its
+// responses and records requests it receives, so tests can check both how
+// code handles API failures and which calls it makes.
+//
+// Typical usage:
+//
+//	srv := twisttest.NewServer("token")
+//	defer srv.Close()
+//	tid := srv.AddThread(channelID, twist.Thread{Title: "Hello"})
+//	client := srv.Client()
+//	thread, err := client.Thread(ctx, tid)
+package twisttest
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artyom/twist"
+)
+
+// Server is a fake Twist API server. Create it with NewServer.
+type Server struct {
+	// URL is a base URL of the server, suitable for twist.WithBaseURL.
+	URL string
+
+	// UserID is an id of the user that owns server token. Reactions added
+	// with the API are attributed to this user. Set it before sending any
+	// requests.
+	UserID uint64
+
+	srv   *httptest.Server
+	token string
+
+	mu         sync.Mutex
+	lastID     uint64
+	workspaces []twist.Workspace
+	channels   map[uint64][]twist.Channel // by workspace id
+	users      map[uint64][]twist.User    // by workspace id
+	threads    map[uint64]*thread         // by thread id
+	groups     map[uint64]twist.Group     // by group id
+	convs      map[uint64]*conversation   // by conversation id
+	faults     []*Fault
+	requests   []Request
+}
+
+type thread struct {
+	twist.Thread
+	comments []twist.Comment // ordered by OrderIndex
+}
+
+type conversation struct {
+	twist.Conversation
+	messages []twist.ConversationMessage // ordered by OrderIndex
+}
+
+// NewServer starts and returns a new Server which expects requests to be
+// authenticated with a given token. Server should be closed once no longer
+// needed.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:    token,
+		channels: make(map[uint64][]twist.Channel),
+		users:    make(map[uint64][]twist.User),
+		threads:  make(map[uint64]*thread),
+		groups:   make(map[uint64]twist.Group),
+		convs:    make(map[uint64]*conversation),
+	}
+	mux := http.NewServeMux()
+	for endpoint, h := range map[string]func(url.Values) (any, error){
+		"v3/workspaces/get":                    s.getWorkspaces,
+		"v3/channels/get":                      s.getChannels,
+		"v3/channels/getone":                   s.getChannel,
+		"v3/channels/add":                      s.addChannel,
+		"v3/channels/update":                   s.updateChannel,
+		"v3/channels/add_users":                s.channelMembers(true),
+		"v3/channels/remove_users":             s.channelMembers(false),
+		"v4/workspace_users/get":               s.getUsers,
+		"v4/workspace_users/getone":            s.getUser,
+		"v4/workspace_users/get_user_by_email": s.getUserByEmail,
+		"v3/threads/getone":                    s.getThread,
+		"v3/threads/get":                       s.getThreads,
+		"v3/threads/add":                       s.addThread,
+		"v3/threads/update":                    s.updateThread,
+		"v3/threads/remove":                    s.removeThread,
+		"v3/comments/get":                      s.getComments,
+		"v3/comments/add":                      s.addComment,
+		"v3/groups/get":                        s.getGroups,
+		"v3/groups/getone":                     s.getGroup,
+		"v3/groups/add":                        s.addGroup,
+		"v3/groups/update":                     s.updateGroup,
+		"v3/groups/remove":                     s.removeGroup,
+		"v3/groups/add_user":                   s.groupMember(true),
+		"v3/groups/remove_user":                s.groupMember(false),
+		"v3/reactions/get":                     s.getReactions,
+		"v3/reactions/add":                     s.react(true),
+		"v3/reactions/remove":                  s.react(false),
+		"v3/inbox/get":                         s.getInbox,
+		"v3/search/query":                      s.search,
+		"v3/inbox/archive":                     s.archiveThread(true),
+		"v3/inbox/unarchive":                   s.archiveThread(false),
+		"v3/inbox/archive_all":                 s.archiveAll,
+		"v3/conversation_messages/get":         s.getConversationMessages,
+		"v3/conversation_messages/add":         s.addConversationMessage,
+	} {
+		mux.Handle("/"+endpoint, s.handler(endpoint, h))
+	}
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() { s.srv.Close() }
+
+// Client returns twist.Client configured to call this server. Client retries
+// failed requests without noticeable delays. Options, if any, are applied
+// after the defaults.
+func (s *Server) Client(opts ...twist.Option) *twist.Client {
+	return twist.New(s.token, append([]twist.Option{
+		twist.WithBaseURL(s.URL),
+		twist.WithHTTPClient(s.srv.Client()),
+		twist.WithRetryPolicy(&twist.BackoffPolicy{MaxAttempts: 10, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+	}, opts...)...)
+}
+
+// AddWorkspace adds a workspace and returns its id. If w.Id is zero, server
+// assigns a new one.
+func (s *Server) AddWorkspace(w twist.Workspace) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Id = s.newID(w.Id)
+	s.workspaces = append(s.workspaces, w)
+	return w.Id
+}
+
+// AddChannel adds a channel to a workspace and returns channel id. If ch.Id
+// is zero, server assigns a new one. Channel WorkspaceID is set to
+// workspaceID.
+func (s *Server) AddChannel(workspaceID uint64, ch twist.Channel) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch.Id = s.newID(ch.Id)
+	ch.WorkspaceID = workspaceID
+	s.channels[workspaceID] = append(s.channels[workspaceID], ch)
+	return ch.Id
+}
+
+// AddUser adds a user to a workspace and returns user id. If u.Id is zero,
+// server assigns a new one.
+func (s *Server) AddUser(workspaceID uint64, u twist.User) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.Id = s.newID(u.Id)
+	s.users[workspaceID] = append(s.users[workspaceID], u)
+	return u.Id
+}
+
+// AddThread adds a thread to a channel and returns thread id. If t.Id is
+// zero, server assigns a new one. Zero timestamps are set to the current
+// time. Thread ChannelID is set to channelID, zero WorkspaceID is set to the
+// workspace of the channel, if it was added with AddChannel.
+func (s *Server) AddThread(channelID uint64, t twist.Thread) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.Id = s.newID(t.Id)
+	now := uint64(time.Now().Unix())
+	t.TsPosted = cmp.Or(t.TsPosted, now)
+	t.TsUpdated = cmp.Or(t.TsUpdated, t.TsPosted)
+	t.ChannelID = channelID
+	for wsID, channels := range s.channels {
+		if t.WorkspaceID == 0 && slices.ContainsFunc(channels, func(ch twist.Channel) bool { return ch.Id == channelID }) {
+			t.WorkspaceID = wsID
+		}
+	}
+	s.threads[t.Id] = &thread{Thread: t}
+	return t.Id
+}
+
+// AddComment adds a comment to the end of a thread and returns comment id.
+// If c.Id is zero, server assigns a new one. Comment OrderIndex is always
+// set to its position in the thread, ThreadID and ChannelID are set to match
+// the thread. Zero timestamp is set to the current time. AddComment panics if
+// thread does not exist.
+func (s *Server) AddComment(threadID uint64, c twist.Comment) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.threads[threadID]
+	if !ok {
+		panic("twisttest: AddComment called for unknown thread " + strconv.FormatUint(threadID, 10))
+	}
+	return s.appendComment(t, c).Id
+}
+
+// appendComment adds comment to the end of a thread. It must be called with
+// s.mu held.
+func (s *Server) appendComment(t *thread, c twist.Comment) twist.Comment {
+	c.Id = s.newID(c.Id)
+	c.ThreadID, c.ChannelID = t.Id, t.ChannelID
+	c.OrderIndex = len(t.comments)
+	c.TsPosted = cmp.Or(c.TsPosted, uint64(time.Now().Unix()))
+	t.comments = append(t.comments, c)
+	t.TsUpdated = max(t.TsUpdated, c.TsPosted)
+	t.CommentCount = len(t.comments)
+	t.LastObjIndex = c.OrderIndex
+	return c
+}
+
+// Comments returns all comments of a thread, including ones posted with
+// twist.Client.AddComment.
+func (s *Server) Comments(threadID uint64) []twist.Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.threads[threadID]; ok {
+		return slices.Clone(t.comments)
+	}
+	return nil
+}
+
+// AddGroup adds a group to a workspace and returns group id. If g.Id is
+// zero, server assigns a new one. Group WorkspaceID is set to workspaceID.
+func (s *Server) AddGroup(workspaceID uint64, g twist.Group) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.Id = s.newID(g.Id)
+	g.WorkspaceID = workspaceID
+	s.groups[g.Id] = g
+	return g.Id
+}
+
+// AddConversation adds a conversation to a workspace and returns
+// conversation id. If c.Id is zero, server assigns a new one. Conversation
+// WorkspaceID is set to workspaceID.
+func (s *Server) AddConversation(workspaceID uint64, c twist.Conversation) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Id = s.newID(c.Id)
+	c.WorkspaceID = workspaceID
+	s.convs[c.Id] = &conversation{Conversation: c}
+	return c.Id
+}
+
+// AddConversationMessage adds a message to the end of a conversation and
+// returns message id. If m.Id is zero, server assigns a new one. Message
+// OrderIndex is always set to its position in the conversation,
+// ConversationID is set to match the conversation. Zero timestamp is set to
+// the current time. AddConversationMessage panics if conversation does not
+// exist.
+func (s *Server) AddConversationMessage(conversationID uint64, m twist.ConversationMessage) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.convs[conversationID]
+	if !ok {
+		panic("twisttest: AddConversationMessage called for unknown conversation " + strconv.FormatUint(conversationID, 10))
+	}
+	return s.appendMessage(c, m).Id
+}
+
+// appendMessage adds message to the end of a conversation. It must be called
+// with s.mu held.
+func (s *Server) appendMessage(c *conversation, m twist.ConversationMessage) twist.ConversationMessage {
+	m.Id = s.newID(m.Id)
+	m.ConversationID = c.Id
+	m.OrderIndex = len(c.messages)
+	m.TsPosted = cmp.Or(m.TsPosted, uint64(time.Now().Unix()))
+	c.messages = append(c.messages, m)
+	c.LastObjIndex = m.OrderIndex
+	c.MessageCount = len(c.messages)
+	return m
+}
+
+// ConversationMessages returns all messages of a conversation, including ones
+// posted with twist.Client.AddConversationMessage.
+func (s *Server) ConversationMessages(conversationID uint64) []twist.ConversationMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.convs[conversationID]; ok {
+		return slices.Clone(c.messages)
+	}
+	return nil
+}
+
+// newID returns id if it's non-zero, or a new unique id otherwise. It must be
+// called with s.mu held.
+func (s *Server) newID(id uint64) uint64 {
+	if id != 0 {
+		s.lastID = max(s.lastID, id)
+		return id
+	}
+	s.lastID++
+	return s.lastID
+}
+
+// Fault describes a failure Server injects into its responses.
+type Fault struct {
+	// Endpoint to inject fault to, i.e. "v3/threads/get". Empty value
+	// matches all endpoints.
+	Endpoint string
+
+	// Times is a number of matching requests to fail. Zero value means
+	// that all matching requests fail.
+	Times int
+
+	// Status is an HTTP status to respond with, i.e. 429 or 503. Requests
+	// failed this way have no effect on server state, unless Committed is
+	// set.
+	Status int
+
+	// Committed makes server process request before responding with
+	// Status, simulating a failure that happens after server has already
+	// applied changes.
+	Committed bool
+
+	// RetryAfter, if set, is sent as a Retry-After header value along with
+	// Status.
+	RetryAfter string
+
+	// BadContentType makes server respond with a text/plain Content-Type
+	// instead of application/json.
+	BadContentType bool
+
+	// UnsortedPage makes server return list results in reverse order.
+	UnsortedPage bool
+
+	// IgnoreFilter makes server ignore filter_by parameter and return
+	// unfiltered results, as real API may do for filters it doesn't
+	// support.
+	IgnoreFilter bool
+}
+
+// InjectFault makes server fail requests as f describes. Faults are matched
+// in the order they were injected.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// takeFault returns fault to apply to request to a given endpoint, if any. It
+// must be called with s.mu held.
+func (s *Server) takeFault(endpoint string) *Fault {
+	for i, f := range s.faults {
+		if f.Endpoint != "" && f.Endpoint != endpoint {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// Request is a request received by Server.
+type Request struct {
+	Method   string
+	Endpoint string     // i.e. "v3/threads/get"
+	Params   url.Values // query and form parameters
+	Header   http.Header
+}
+
+// Requests returns all requests server received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// RequestCount returns the number of requests server received to a given
+// endpoint.
+func (s *Server) RequestCount(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, r := range s.requests {
+		if r.Endpoint == endpoint {
+			n++
+		}
+	}
+	return n
+}
+
+// apiError is an error response in Twist API format. Fake server uses HTTP
+// status as error code.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func notFound(what string) error { return &apiError{http.StatusNotFound, what + " not found"} }
+
+func badRequest(msg string) error { return &apiError{http.StatusBadRequest, msg} }
+
+func (s *Server) handler(endpoint string, fn func(url.Values) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, badRequest(err.Error()))
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method:   r.Method,
+			Endpoint: endpoint,
+			Params:   r.Form,
+			Header:   r.Header.Clone(),
+		})
+		fault := s.takeFault(endpoint)
+		if fault != nil && fault.Status != 0 {
+			if fault.Committed && r.Header.Get("Authorization") == "Bearer "+s.token {
+				fn(r.Form)
+			}
+			s.mu.Unlock()
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(w, &apiError{fault.Status, http.StatusText(fault.Status)})
+			return
+		}
+		var out any
+		var err error
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			err = &apiError{http.StatusUnauthorized, "Invalid token"}
+		} else {
+			params := r.Form
+			if fault != nil && fault.IgnoreFilter {
+				params = maps.Clone(params)
+				delete(params, "filter_by")
+			}
+			out, err = fn(params)
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if fault != nil && fault.UnsortedPage {
+			out = reversed(out)
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			writeError(w, &apiError{http.StatusInternalServerError, err.Error()})
+			return
+		}
+		if fault != nil && fault.BadContentType {
+			w.Header().Set("Content-Type", "text/plain")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write(b)
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = &apiError{http.StatusInternalServerError, err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	json.NewEncoder(w).Encode(struct {
+		Code    int    `json:"error_code"`
+		Message string `json:"error_string"`
+	}{e.status, e.message})
+}
+
+func reversed(v any) any {
+	switch v := v.(type) {
+	case []twist.Thread:
+		slices.Reverse(v)
+	case []twist.Comment:
+		slices.Reverse(v)
+	case []twist.ConversationMessage:
+		slices.Reverse(v)
+	}
+	return v
+}
+
+func (s *Server) getWorkspaces(url.Values) (any, error) {
+	return listOf(s.workspaces), nil
+}
+
+func (s *Server) getChannels(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	return listOf(s.channels[id]), nil
+}
+
+// channel returns a channel with a given id. It must be called with s.mu
+// held.
+func (s *Server) channel(id uint64) (*twist.Channel, error) {
+	for _, channels := range s.channels {
+		for i := range channels {
+			if channels[i].Id == id {
+				return &channels[i], nil
+			}
+		}
+	}
+	return nil, notFound("channel")
+}
+
+func (s *Server) getChannel(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	return s.channel(id)
+}
+
+func (s *Server) addChannel(vals url.Values) (any, error) {
+	wsID, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	ch := twist.Channel{
+		Id:          s.newID(0),
+		WorkspaceID: wsID,
+		Name:        vals.Get("name"),
+		Description: vals.Get("description"),
+		TsCreated:   uint64(time.Now().Unix()),
+		Public:      vals.Get("public") == "true",
+	}
+	if ch.Name == "" {
+		return nil, badRequest("empty name")
+	}
+	if ch.Color, err = strconv.Atoi(cmp.Or(vals.Get("color"), "0")); err != nil {
+		return nil, badRequest("invalid color")
+	}
+	if ch.Members, err = idsParam(vals, "user_ids"); err != nil {
+		return nil, err
+	}
+	if ch.DefaultGroups, err = idsParam(vals, "default_groups"); err != nil {
+		return nil, err
+	}
+	s.channels[wsID] = append(s.channels[wsID], ch)
+	return ch, nil
+}
+
+func (s *Server) updateChannel(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	ch, err := s.channel(id)
+	if err != nil {
+		return nil, err
+	}
+	upd := *ch
+	if vals.Has("name") {
+		upd.Name = vals.Get("name")
+	}
+	if vals.Has("description") {
+		upd.Description = vals.Get("description")
+	}
+	if vals.Has("color") {
+		if upd.Color, err = strconv.Atoi(vals.Get("color")); err != nil {
+			return nil, badRequest("invalid color")
+		}
+	}
+	if vals.Has("public") {
+		upd.Public = vals.Get("public") == "true"
+	}
+	if vals.Has("default_groups") {
+		if upd.DefaultGroups, err = idsParam(vals, "default_groups"); err != nil {
+			return nil, err
+		}
+	}
+	*ch = upd
+	return upd, nil
+}
+
+// channelMembers returns handler for channels/add_users (if add is true)
+// and channels/remove_users.
+func (s *Server) channelMembers(add bool) func(url.Values) (any, error) {
+	return func(vals url.Values) (any, error) {
+		id, err := uintParam(vals, "id")
+		if err != nil {
+			return nil, err
+		}
+		ch, err := s.channel(id)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := idsParam(vals, "user_ids")
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, badRequest("empty user_ids")
+		}
+		members := slices.DeleteFunc(slices.Clone(ch.Members), func(id uint64) bool { return slices.Contains(ids, id) })
+		if add {
+			members = append(members, ids...)
+		}
+		ch.Members = members
+		return struct{}{}, nil
+	}
+}
+
+func (s *Server) getUsers(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	return listOf(s.users[id]), nil
+}
+
+func (s *Server) getUser(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	uid, err := uintParam(vals, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range s.users[id] {
+		if u.Id == uid {
+			return u, nil
+		}
+	}
+	return nil, notFound("user")
+}
+
+func (s *Server) getUserByEmail(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	email := vals.Get("email")
+	for _, u := range s.users[id] {
+		if email != "" && strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, notFound("user")
+}
+
+func (s *Server) getGroup(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	g, ok := s.groups[id]
+	if !ok {
+		return nil, notFound("group")
+	}
+	return g, nil
+}
+
+func (s *Server) getGroups(vals url.Values) (any, error) {
+	wsID, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	var out []twist.Group
+	for _, id := range slices.Sorted(maps.Keys(s.groups)) {
+		if g := s.groups[id]; g.WorkspaceID == wsID {
+			out = append(out, g)
+		}
+	}
+	return listOf(out), nil
+}
+
+func (s *Server) addGroup(vals url.Values) (any, error) {
+	wsID, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	g := twist.Group{WorkspaceID: wsID, Name: vals.Get("name"), Description: vals.Get("description")}
+	if g.Name == "" {
+		return nil, badRequest("empty name")
+	}
+	if g.Members, err = idsParam(vals, "user_ids"); err != nil {
+		return nil, err
+	}
+	g.Id = s.newID(0)
+	s.groups[g.Id] = g
+	return g, nil
+}
+
+func (s *Server) updateGroup(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	g, ok := s.groups[id]
+	if !ok {
+		return nil, notFound("group")
+	}
+	if vals.Has("name") {
+		g.Name = vals.Get("name")
+	}
+	if vals.Has("description") {
+		g.Description = vals.Get("description")
+	}
+	s.groups[id] = g
+	return g, nil
+}
+
+func (s *Server) removeGroup(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.groups[id]; !ok {
+		return nil, notFound("group")
+	}
+	delete(s.groups, id)
+	return struct{}{}, nil
+}
+
+// groupMember returns handler for groups/add_user (if add is true) and
+// groups/remove_user.
+func (s *Server) groupMember(add bool) func(url.Values) (any, error) {
+	return func(vals url.Values) (any, error) {
+		id, err := uintParam(vals, "id")
+		if err != nil {
+			return nil, err
+		}
+		uid, err := uintParam(vals, "user_id")
+		if err != nil {
+			return nil, err
+		}
+		g, ok := s.groups[id]
+		if !ok {
+			return nil, notFound("group")
+		}
+		g.Members = slices.DeleteFunc(slices.Clone(g.Members), func(id uint64) bool { return id == uid })
+		if add {
+			g.Members = append(g.Members, uid)
+		}
+		s.groups[id] = g
+		return struct{}{}, nil
+	}
+}
+
+func (s *Server) addComment(vals url.Values) (any, error) {
+	threadID, err := uintParam(vals, "thread_id")
+	if err != nil {
+		return nil, err
+	}
+	t, ok := s.threads[threadID]
+	if !ok {
+		return nil, notFound("thread")
+	}
+	if vals.Get("content") == "" {
+		return nil, badRequest("empty content")
+	}
+	return s.appendComment(t, twist.Comment{Text: vals.Get("content")}), nil
+}
+
+func (s *Server) addConversationMessage(vals url.Values) (any, error) {
+	convID, err := uintParam(vals, "conversation_id")
+	if err != nil {
+		return nil, err
+	}
+	c, ok := s.convs[convID]
+	if !ok {
+		return nil, notFound("conversation")
+	}
+	if vals.Get("content") == "" {
+		return nil, badRequest("empty content")
+	}
+	return s.appendMessage(c, twist.ConversationMessage{Text: vals.Get("content")}), nil
+}
+
+func (s *Server) getThread(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	t, ok := s.threads[id]
+	if !ok {
+		return nil, notFound("thread")
+	}
+	return t.Thread, nil
+}
+
+func (s *Server) addThread(vals url.Values) (any, error) {
+	chID, err := uintParam(vals, "channel_id")
+	if err != nil {
+		return nil, err
+	}
+	ch, err := s.channel(chID)
+	if err != nil {
+		return nil, err
+	}
+	now := uint64(time.Now().Unix())
+	t := twist.Thread{
+		ChannelID:   chID,
+		WorkspaceID: ch.WorkspaceID,
+		Title:       vals.Get("title"),
+		Text:        vals.Get("content"),
+		Creator:     s.UserID,
+		TsPosted:    now,
+		TsUpdated:   now,
+	}
+	if t.Title == "" {
+		return nil, badRequest("empty title")
+	}
+	if t.Recipients, err = idsParam(vals, "recipients"); err != nil {
+		return nil, err
+	}
+	if t.Groups, err = idsParam(vals, "groups"); err != nil {
+		return nil, err
+	}
+	if t.Attachments, err = attachmentsParam(vals); err != nil {
+		return nil, err
+	}
+	t.Id = s.newID(0)
+	s.threads[t.Id] = &thread{Thread: t}
+	return t, nil
+}
+
+func (s *Server) updateThread(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	t, ok := s.threads[id]
+	if !ok {
+		return nil, notFound("thread")
+	}
+	upd := t.Thread
+	if vals.Has("title") {
+		upd.Title = vals.Get("title")
+	}
+	if vals.Has("content") {
+		upd.Text = vals.Get("content")
+	}
+	if vals.Has("attachments") {
+		if upd.Attachments, err = attachmentsParam(vals); err != nil {
+			return nil, err
+		}
+	}
+	upd.TsEdited = uint64(time.Now().Unix())
+	t.Thread = upd
+	return upd, nil
+}
+
+func (s *Server) removeThread(vals url.Values) (any, error) {
+	id, err := uintParam(vals, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.threads[id]; !ok {
+		return nil, notFound("thread")
+	}
+	delete(s.threads, id)
+	return struct{}{}, nil
+}
+
+// getThreads implements threads/get: if after_id is set, threads are sorted
+// by id, otherwise by last update time, most recent first. Only "is_pinned"
+// and "is_starred" values of filter_by are supported.
+func (s *Server) getThreads(vals url.Values) (any, error) {
+	channelID, err := uintParam(vals, "channel_id")
+	if err != nil {
+		return nil, err
+	}
+	limit, err := limitParam(vals, 20)
+	if err != nil {
+		return nil, err
+	}
+	out := []twist.Thread{}
+	for _, t := range s.threads {
+		if t.ChannelID == channelID {
+			out = append(out, t.Thread)
+		}
+	}
+	switch vals.Get("filter_by") {
+	case "", "everyone":
+	case "is_pinned":
+		out = slices.DeleteFunc(out, func(t twist.Thread) bool { return !t.Pinned })
+	case "is_starred":
+		out = slices.DeleteFunc(out, func(t twist.Thread) bool { return !t.Starred })
+	default:
+		return nil, badRequest("unsupported filter_by")
+	}
+	if v := vals.Get("newer_than_ts"); v != "" {
+		ts, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid newer_than_ts")
+		}
+		out = slices.DeleteFunc(out, func(t twist.Thread) bool { return t.TsUpdated < ts })
+	}
+	if v := vals.Get("after_id"); v != "" {
+		var afterID uint64
+		if v != "-1" {
+			if afterID, err = strconv.ParseUint(v, 10, 64); err != nil {
+				return nil, badRequest("invalid after_id")
+			}
+		}
+		out = slices.DeleteFunc(out, func(t twist.Thread) bool { return t.Id <= afterID })
+		slices.SortFunc(out, func(a, b twist.Thread) int { return cmp.Compare(a.Id, b.Id) })
+		if vals.Get("order_by") == "desc" {
+			slices.Reverse(out)
+		}
+	} else {
+		slices.SortFunc(out, func(a, b twist.Thread) int {
+			return cmp.Or(cmp.Compare(b.TsUpdated, a.TsUpdated), cmp.Compare(a.Id, b.Id))
+		})
+		if vals.Get("order_by") == "asc" {
+			slices.Reverse(out)
+		}
+	}
+	return out[:min(len(out), limit)], nil
+}
+
+// getInbox implements inbox/get: threads of a workspace, most recently
+// updated first.
+func (s *Server) getInbox(vals url.Values) (any, error) {
+	wsID, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	limit, err := limitParam(vals, 20)
+	if err != nil {
+		return nil, err
+	}
+	var newerThan, olderThan uint64
+	for name, dst := range map[string]*uint64{"newer_than_ts": &newerThan, "older_than_ts": &olderThan} {
+		if v := vals.Get(name); v != "" {
+			if *dst, err = strconv.ParseUint(v, 10, 64); err != nil {
+				return nil, badRequest("invalid " + name)
+			}
+		}
+	}
+	archived := vals.Get("archived") == "true"
+	out := []twist.Thread{}
+	for _, t := range s.threads {
+		if t.WorkspaceID != wsID || t.Archived != archived || t.TsUpdated < newerThan ||
+			olderThan != 0 && t.TsUpdated > olderThan {
+			continue
+		}
+		out = append(out, t.Thread)
+	}
+	slices.SortFunc(out, func(a, b twist.Thread) int {
+		return cmp.Or(cmp.Compare(b.TsUpdated, a.TsUpdated), cmp.Compare(b.Id, a.Id))
+	})
+	return out[:min(len(out), limit)], nil
+}
+
+// search implements search/query with case-insensitive substring matching.
+// Results are ordered by thread (or conversation) id, thread matches go
+// before its comments; cursor_mark is an offset in this list.
+func (s *Server) search(vals url.Values) (any, error) {
+	wsID, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(vals.Get("query"))
+	if query == "" {
+		return nil, badRequest("empty query")
+	}
+	limit, err := limitParam(vals, 20)
+	if err != nil {
+		return nil, err
+	}
+	var channelIDs, convIDs, authorIDs []uint64
+	for name, dst := range map[string]*[]uint64{"channel_ids": &channelIDs, "conversation_ids": &convIDs, "author_ids": &authorIDs} {
+		if *dst, err = idsParam(vals, name); err != nil {
+			return nil, err
+		}
+	}
+	var dateFrom, dateTo string
+	for name, dst := range map[string]*string{"date_from": &dateFrom, "date_to": &dateTo} {
+		if v := vals.Get(name); v != "" {
+			if _, err := time.Parse(time.DateOnly, v); err != nil {
+				return nil, badRequest("invalid " + name)
+			}
+			*dst = v
+		}
+	}
+	typ := twist.SearchResultType(vals.Get("type"))
+	titleOnly := vals.Get("title_only") == "true"
+	var offset int
+	if v := vals.Get("cursor_mark"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return nil, badRequest("invalid cursor_mark")
+		}
+	}
+
+	keep := func(t twist.SearchResultType, text string, author, ts uint64) bool {
+		date := time.Unix(int64(ts), 0).UTC().Format(time.DateOnly)
+		return (typ == "" || typ == t) && strings.Contains(strings.ToLower(text), query) &&
+			(len(authorIDs) == 0 || slices.Contains(authorIDs, author)) &&
+			(dateFrom == "" || date >= dateFrom) && (dateTo == "" || date <= dateTo)
+	}
+	var items []twist.SearchResult
+	for _, id := range slices.Sorted(maps.Keys(s.threads)) {
+		t := s.threads[id]
+		if t.WorkspaceID != wsID || len(convIDs) != 0 ||
+			len(channelIDs) != 0 && !slices.Contains(channelIDs, t.ChannelID) {
+			continue
+		}
+		text := t.Title
+		if !titleOnly {
+			text += "\n" + t.Text
+		}
+		if keep(twist.SearchThread, text, t.Creator, t.TsUpdated) {
+			items = append(items, twist.SearchResult{Type: twist.SearchThread, ChannelID: t.ChannelID,
+				ThreadID: t.Id, Title: t.Title, Snippet: t.Text, SnippetCreator: t.Creator, TsSnippet: t.TsUpdated})
+		}
+		if titleOnly {
+			continue
+		}
+		for _, c := range t.comments {
+			if keep(twist.SearchComment, c.Text, c.Creator, c.TsPosted) {
+				items = append(items, twist.SearchResult{Type: twist.SearchComment, ChannelID: t.ChannelID,
+					ThreadID: t.Id, CommentID: c.Id, Title: t.Title, Snippet: c.Text, SnippetCreator: c.Creator,
+					TsSnippet: c.TsPosted})
+			}
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(s.convs)) {
+		c := s.convs[id]
+		if c.WorkspaceID != wsID || titleOnly || len(channelIDs) != 0 ||
+			len(convIDs) != 0 && !slices.Contains(convIDs, c.Id) {
+			continue
+		}
+		for _, m := range c.messages {
+			if keep(twist.SearchMessage, m.Text, m.Creator, m.TsPosted) {
+				items = append(items, twist.SearchResult{Type: twist.SearchMessage, ConversationID: c.Id,
+					MessageID: m.Id, Snippet: m.Text, SnippetCreator: m.Creator, TsSnippet: m.TsPosted})
+			}
+		}
+	}
+	items = items[min(offset, len(items)):]
+	out := struct {
+		Items      []twist.SearchResult `json:"items"`
+		HasMore    bool                 `json:"has_more"`
+		NextCursor string               `json:"next_cursor_mark,omitempty"`
+	}{Items: listOf(items[:min(len(items), limit)]), HasMore: len(items) > limit}
+	if out.HasMore {
+		out.NextCursor = strconv.Itoa(offset + limit)
+	}
+	return out, nil
+}
+
+func (s *Server) archiveThread(archived bool) func(url.Values) (any, error) {
+	return func(vals url.Values) (any, error) {
+		id, err := uintParam(vals, "id")
+		if err != nil {
+			return nil, err
+		}
+		t, ok := s.threads[id]
+		if !ok {
+			return nil, notFound("thread")
+		}
+		t.Archived = archived
+		return struct{}{}, nil
+	}
+}
+
+// reactions returns reactions of an object identified by exactly one of
+// thread_id, comment_id or message_id parameters. It must be called with s.mu
+// held.
+func (s *Server) reactions(vals url.Values) (*twist.Reactions, error) {
+	var params []string
+	for _, p := range []string{"thread_id", "comment_id", "message_id"} {
+		if vals.Has(p) {
+			params = append(params, p)
+		}
+	}
+	if len(params) != 1 {
+		return nil, badRequest("want exactly one of thread_id, comment_id, message_id")
+	}
+	id, err := uintParam(vals, params[0])
+	if err != nil {
+		return nil, err
+	}
+	switch params[0] {
+	case "thread_id":
+		if t, ok := s.threads[id]; ok {
+			return &t.Reactions, nil
+		}
+		return nil, notFound("thread")
+	case "comment_id":
+		for _, t := range s.threads {
+			for i := range t.comments {
+				if t.comments[i].Id == id {
+					return &t.comments[i].Reactions, nil
+				}
+			}
+		}
+		return nil, notFound("comment")
+	default:
+		for _, c := range s.convs {
+			for i := range c.messages {
+				if c.messages[i].Id == id {
+					return &c.messages[i].Reactions, nil
+				}
+			}
+		}
+		return nil, notFound("message")
+	}
+}
+
+func (s *Server) getReactions(vals url.Values) (any, error) {
+	r, err := s.reactions(vals)
+	if err != nil {
+		return nil, err
+	}
+	if *r == nil {
+		return twist.Reactions{}, nil
+	}
+	return maps.Clone(*r), nil
+}
+
+// react returns handler for reactions/add (if add is true) and
+// reactions/remove.
+func (s *Server) react(add bool) func(url.Values) (any, error) {
+	return func(vals url.Values) (any, error) {
+		emoji := vals.Get("reaction")
+		if emoji == "" {
+			return nil, badRequest("empty reaction")
+		}
+		r, err := s.reactions(vals)
+		if err != nil {
+			return nil, err
+		}
+		uids := slices.DeleteFunc(slices.Clone((*r)[emoji]), func(id uint64) bool { return id == s.UserID })
+		if add {
+			uids = append(uids, s.UserID)
+		}
+		if *r == nil {
+			*r = make(twist.Reactions)
+		}
+		if len(uids) == 0 {
+			delete(*r, emoji)
+		} else {
+			(*r)[emoji] = uids
+		}
+		return struct{}{}, nil
+	}
+}
+
+func (s *Server) archiveAll(vals url.Values) (any, error) {
+	wsID, err := uintParam(vals, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	var olderThan uint64
+	if v := vals.Get("older_than_ts"); v != "" {
+		if olderThan, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, badRequest("invalid older_than_ts")
+		}
+	}
+	for _, t := range s.threads {
+		if t.WorkspaceID == wsID && (olderThan == 0 || t.TsUpdated <= olderThan) {
+			t.Archived = true
+		}
+	}
+	return struct{}{}, nil
+}
+
+// getComments implements comments/get: comments are selected either by
+// {from,to}_obj_index range, or with newer_than_ts, in order of their
+// OrderIndex.
+func (s *Server) getComments(vals url.Values) (any, error) {
+	threadID, err := uintParam(vals, "thread_id")
+	if err != nil {
+		return nil, err
+	}
+	t, ok := s.threads[threadID]
+	if !ok {
+		return nil, notFound("thread")
+	}
+	w, err := windowParams(vals, len(t.comments))
+	if err != nil {
+		return nil, err
+	}
+	out := []twist.Comment{}
+	for _, c := range t.comments {
+		if w.match(c.OrderIndex, c.TsPosted) {
+			out = append(out, c)
+		}
+	}
+	return out[:min(len(out), w.limit)], nil
+}
+
+func (s *Server) getConversationMessages(vals url.Values) (any, error) {
+	convID, err := uintParam(vals, "conversation_id")
+	if err != nil {
+		return nil, err
+	}
+	c, ok := s.convs[convID]
+	if !ok {
+		return nil, notFound("conversation")
+	}
+	w, err := windowParams(vals, len(c.messages))
+	if err != nil {
+		return nil, err
+	}
+	out := []twist.ConversationMessage{}
+	for _, m := range c.messages {
+		if w.match(m.OrderIndex, m.TsPosted) {
+			out = append(out, m)
+		}
+	}
+	return out[:min(len(out), w.limit)], nil
+}
+
+// window selects a part of thread comments or conversation messages.
+type window struct {
+	from, to int    // inclusive range of OrderIndex
+	since    uint64 // minimal TsPosted
+	limit    int
+}
+
+func (w window) match(orderIndex int, tsPosted uint64) bool {
+	return orderIndex >= w.from && orderIndex <= w.to && tsPosted >= w.since
+}
+
+// windowParams parses {from,to}_obj_index, newer_than_ts and limit
+// parameters for a list of n objects.
+func windowParams(vals url.Values, n int) (window, error) {
+	w := window{to: n - 1}
+	var err error
+	if w.limit, err = limitParam(vals, 20); err != nil {
+		return w, err
+	}
+	if v := vals.Get("from_obj_index"); v != "" {
+		if w.from, err = strconv.Atoi(v); err != nil {
+			return w, badRequest("invalid from_obj_index")
+		}
+	}
+	if v := vals.Get("to_obj_index"); v != "" {
+		if w.to, err = strconv.Atoi(v); err != nil {
+			return w, badRequest("invalid to_obj_index")
+		}
+	}
+	if v := vals.Get("newer_than_ts"); v != "" {
+		if w.since, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return w, badRequest("invalid newer_than_ts")
+		}
+	}
+	return w, nil
+}
+
+// listOf returns a copy of s that is never nil, so it's encoded as an empty
+// JSON array rather than null.
+func listOf[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return slices.Clone(s)
+}
+
+func uintParam(vals url.Values, name string) (uint64, error) {
+	v, err := strconv.ParseUint(vals.Get(name), 10, 64)
+	if err != nil || v == 0 {
+		return 0, badRequest("invalid " + name)
+	}
+	return v, nil
+}
+
+// idsParam parses a JSON list of ids, which is nil if parameter is not set.
+func idsParam(vals url.Values, name string) ([]uint64, error) {
+	v := vals.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	var ids []uint64
+	if err := json.Unmarshal([]byte(v), &ids); err != nil {
+		return nil, badRequest("invalid " + name)
+	}
+	return ids, nil
+}
+
+// attachmentsParam parses a JSON list of attachments, which is nil if
+// parameter is not set.
+func attachmentsParam(vals url.Values) ([]twist.Attachment, error) {
+	v := vals.Get("attachments")
+	if v == "" {
+		return nil, nil
+	}
+	var out []twist.Attachment
+	if err := json.Unmarshal([]byte(v), &out); err != nil {
+		return nil, badRequest("invalid attachments")
+	}
+	return out, nil
+}
+
+func limitParam(vals url.Values, def int) (int, error) {
+	v := strings.TrimSpace(vals.Get("limit"))
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, badRequest("invalid limit")
+	}
+	return n, nil
+}
@@ -0,0 +1,43 @@
+package twisttest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artyom/twist"
+	"github.com/artyom/twist/twisttest"
+)
+
+func TestServer_faultedWrite(t *testing.T) {
+	srv := twisttest.NewServer("token")
+	defer srv.Close()
+	ws := srv.AddWorkspace(twist.Workspace{Name: "Test"})
+	ch := srv.AddChannel(ws, twist.Channel{Name: "General"})
+	tid := srv.AddThread(ch, twist.Thread{Title: "Hello"})
+	conv := srv.AddConversation(ws, twist.Conversation{})
+
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/comments/add", Times: 1, Status: 500})
+	srv.InjectFault(twisttest.Fault{Endpoint: "v3/conversation_messages/add", Times: 1, Status: 500})
+	client := srv.Client(twist.WithRetryPolicy(nil))
+	ctx := context.Background()
+	if _, err := client.AddComment(ctx, twist.NewComment{ThreadID: tid, Content: "hi"}); err == nil {
+		t.Fatal("AddComment succeeded despite injected fault")
+	}
+	if cs := srv.Comments(tid); len(cs) != 0 {
+		t.Fatalf("faulted request added comments: %+v", cs)
+	}
+	if _, err := client.AddConversationMessage(ctx, twist.NewConversationMessage{ConversationID: conv, Content: "hi"}); err == nil {
+		t.Fatal("AddConversationMessage succeeded despite injected fault")
+	}
+	if ms := srv.ConversationMessages(conv); len(ms) != 0 {
+		t.Fatalf("faulted request added messages: %+v", ms)
+	}
+
+	// once faults are used up, writes succeed
+	if _, err := client.AddComment(ctx, twist.NewComment{ThreadID: tid, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if cs := srv.Comments(tid); len(cs) != 1 {
+		t.Fatalf("got %d comments, want 1", len(cs))
+	}
+}
@@ -0,0 +1,53 @@
+package twist
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// User returns a single user of a given workspace.
+func (c *Client) User(ctx context.Context, workspaceID, userID uint64) (*User, error) {
+	if workspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	if userID == 0 {
+		return nil, errors.New("invalid user id")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(workspaceID, 10))
+	vals.Add("user_id", strconv.FormatUint(userID, 10))
+	var out User
+	if err := c.get(ctx, "v4/workspace_users/getone", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UserByEmail returns a user of a given workspace with a given email.
+func (c *Client) UserByEmail(ctx context.Context, workspaceID uint64, email string) (*User, error) {
+	if workspaceID == 0 {
+		return nil, errors.New("invalid workspace id")
+	}
+	if email == "" {
+		return nil, errors.New("empty email")
+	}
+	vals := make(url.Values)
+	vals.Add("id", strconv.FormatUint(workspaceID, 10))
+	vals.Add("email", email)
+	var out User
+	if err := c.get(ctx, "v4/workspace_users/get_user_by_email", vals, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Me returns the user that owns Client's token.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var out User
+	if err := c.get(ctx, "v3/users/get_session_user", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,228 @@
+// Package webhook implements a server side of Twist outgoing webhooks and
+// bots.
+//
+// Twist calls outgoing webhook (or bot) URL when a new thread, comment, or
+// conversation message is posted. Handler verifies such requests, decodes
+// them into typed events, and dispatches them to registered callbacks, which
+// can reply synchronously by returning a non-nil Reply.
+//
+// Typical usage:
+//
+//	h := webhook.NewHandler(os.Getenv("TWIST_VERIFY_TOKEN"))
+//	h.OnComment(func(ctx context.Context, e *webhook.CommentEvent) (*webhook.Reply, error) {
+//		if strings.Contains(e.Comment.Text, "/deploy") {
+//			return &webhook.Reply{Content: "Deploying…"}, nil
+//		}
+//		return nil, nil
+//	})
+//	http.ListenAndServe(addr, h)
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/artyom/twist"
+)
+
+// Handler is an http.Handler that receives Twist outgoing webhook and bot
+// requests. Create it with NewHandler. Register callbacks before Handler
+// starts serving requests.
+type Handler struct {
+	// ErrorLog, if set, is used to log errors returned by callbacks.
+	ErrorLog *log.Logger
+
+	verifyToken string
+
+	onThread  func(context.Context, *ThreadEvent) (*Reply, error)
+	onComment func(context.Context, *CommentEvent) (*Reply, error)
+	onMessage func(context.Context, *MessageEvent) (*Reply, error)
+}
+
+// NewHandler returns Handler that only accepts requests carrying a given
+// verify token, which Twist shows on integration configuration page.
+func NewHandler(verifyToken string) *Handler { return &Handler{verifyToken: verifyToken} }
+
+// OnThread registers callback called for new threads.
+func (h *Handler) OnThread(fn func(context.Context, *ThreadEvent) (*Reply, error)) { h.onThread = fn }
+
+// OnComment registers callback called for new thread comments.
+func (h *Handler) OnComment(fn func(context.Context, *CommentEvent) (*Reply, error)) {
+	h.onComment = fn
+}
+
+// OnMessage registers callback called for new conversation messages.
+func (h *Handler) OnMessage(fn func(context.Context, *MessageEvent) (*Reply, error)) {
+	h.onMessage = fn
+}
+
+// Event holds fields common to all events.
+type Event struct {
+	Type        string // event type, i.e. "comment_added"
+	WorkspaceID uint64
+	UserID      uint64 // author of the content that triggered event
+	UserName    string
+
+	// CallbackURL, if set, is a URL to post asynchronous replies to.
+	CallbackURL string
+
+	// Raw holds all request parameters, including ones that Event and its
+	// embedding types don't model.
+	Raw url.Values
+}
+
+// ThreadEvent is sent when a new thread is posted.
+type ThreadEvent struct {
+	Event
+	Thread twist.Thread // only fields present in webhook request are set
+}
+
+// CommentEvent is sent when a new comment is posted to a thread.
+type CommentEvent struct {
+	Event
+	Comment     twist.Comment // only fields present in webhook request are set
+	ThreadTitle string
+}
+
+// MessageEvent is sent when a new message is posted to a conversation.
+type MessageEvent struct {
+	Event
+	Message twist.ConversationMessage // only fields present in webhook request are set
+}
+
+// Reply is a synchronous reply to an event, posted by Twist to the same
+// thread or conversation.
+type Reply struct {
+	Content string `json:"content"`
+}
+
+// ServeHTTP implements http.Handler. It responds with 401 Unauthorized to
+// requests with invalid verify token, and with 500 Internal Server Error if
+// callback returns an error.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	vals, err := readParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.verifyToken == "" || subtle.ConstantTimeCompare([]byte(vals.Get("verify_token")), []byte(h.verifyToken)) != 1 {
+		http.Error(w, "invalid verify token", http.StatusUnauthorized)
+		return
+	}
+	vals.Del("verify_token")
+	reply, err := h.dispatch(r.Context(), vals)
+	if err != nil {
+		if h.ErrorLog != nil {
+			h.ErrorLog.Printf("twist webhook %s event: %v", vals.Get("event_type"), err)
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if reply == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
+
+// dispatch decodes event from request parameters and calls a matching
+// callback. Event kind is detected by the ids present, since bot requests
+// don't carry specific event types.
+func (h *Handler) dispatch(ctx context.Context, vals url.Values) (*Reply, error) {
+	id := func(key string) uint64 {
+		v, _ := strconv.ParseUint(vals.Get(key), 10, 64)
+		return v
+	}
+	ev := Event{
+		Type:        vals.Get("event_type"),
+		WorkspaceID: id("workspace_id"),
+		UserID:      id("user_id"),
+		UserName:    vals.Get("user_name"),
+		CallbackURL: vals.Get("url_callback"),
+		Raw:         vals,
+	}
+	switch {
+	case id("conversation_id") != 0:
+		if h.onMessage == nil {
+			return nil, nil
+		}
+		return h.onMessage(ctx, &MessageEvent{Event: ev, Message: twist.ConversationMessage{
+			Id:             id("message_id"),
+			ConversationID: id("conversation_id"),
+			Text:           vals.Get("content"),
+			Creator:        ev.UserID,
+			CreatorName:    ev.UserName,
+		}})
+	case id("comment_id") != 0:
+		if h.onComment == nil {
+			return nil, nil
+		}
+		return h.onComment(ctx, &CommentEvent{Event: ev, ThreadTitle: vals.Get("thread_title"), Comment: twist.Comment{
+			Id:          id("comment_id"),
+			ThreadID:    id("thread_id"),
+			ChannelID:   id("channel_id"),
+			WorkspaceID: ev.WorkspaceID,
+			Text:        vals.Get("content"),
+			Creator:     ev.UserID,
+		}})
+	case id("thread_id") != 0:
+		if h.onThread == nil {
+			return nil, nil
+		}
+		return h.onThread(ctx, &ThreadEvent{Event: ev, Thread: twist.Thread{
+			Id:          id("thread_id"),
+			ChannelID:   id("channel_id"),
+			WorkspaceID: ev.WorkspaceID,
+			Title:       vals.Get("thread_title"),
+			Text:        vals.Get("content"),
+			Creator:     ev.UserID,
+		}})
+	}
+	return nil, nil // i.e. "ping" event
+}
+
+// readParams returns request parameters sent either as a form, or as a JSON
+// object.
+func readParams(r *http.Request) (url.Values, error) {
+	mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediatype != "application/json" {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return r.Form, nil
+	}
+	var obj map[string]any
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.UseNumber() // ids may not fit into float64 precisely
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("decoding request body: %w", err)
+	}
+	vals := make(url.Values, len(obj))
+	for k, v := range obj {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			vals.Set(k, v)
+		case json.Number:
+			vals.Set(k, v.String())
+		default:
+			b, _ := json.Marshal(v)
+			vals.Set(k, string(b))
+		}
+	}
+	return vals, nil
+}
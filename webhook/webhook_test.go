@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h := NewHandler("secret")
+	h.OnComment(func(_ context.Context, e *CommentEvent) (*Reply, error) {
+		if e.Comment.ThreadID != 12 || e.Comment.Creator != 34 {
+			t.Errorf("unexpected event: %+v", e)
+		}
+		return &Reply{Content: "echo: " + e.Comment.Text}, nil
+	})
+
+	send := func(vals url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(vals.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	vals := url.Values{
+		"event_type":   {"comment_added"},
+		"verify_token": {"secret"},
+		"thread_id":    {"12"},
+		"comment_id":   {"56"},
+		"user_id":      {"34"},
+		"content":      {"/deploy"},
+	}
+	w := send(vals)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	var reply Reply
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "echo: /deploy" {
+		t.Fatalf("got reply %q", reply.Content)
+	}
+
+	vals.Set("verify_token", "wrong")
+	if w := send(vals); w.Code != http.StatusUnauthorized {
+		t.Fatalf("request with wrong token got status %d, want 401", w.Code)
+	}
+}
+
+func TestHandler_json(t *testing.T) {
+	h := NewHandler("secret")
+	var got *ThreadEvent
+	h.OnThread(func(_ context.Context, e *ThreadEvent) (*Reply, error) {
+		got = e
+		return nil, nil
+	})
+	// ids above 2^53 lose precision if decoded as float64
+	const body = `{
+		"event_type": "thread_added",
+		"verify_token": "secret",
+		"workspace_id": 9007199254740993,
+		"channel_id": 12,
+		"thread_id": 18014398509481985,
+		"thread_title": "Release",
+		"user_id": 34,
+		"user_name": "Maria",
+		"content": "v1.2 is out",
+		"attachments": [{"title": "notes"}],
+		"url_callback": null
+	}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	if got == nil {
+		t.Fatal("thread callback was not called")
+	}
+	if got.WorkspaceID != 9007199254740993 || got.Thread.Id != 18014398509481985 || got.Thread.WorkspaceID != got.WorkspaceID {
+		t.Errorf("large ids were not decoded precisely: %+v", got)
+	}
+	if got.Type != "thread_added" || got.UserName != "Maria" || got.Thread.Title != "Release" ||
+		got.Thread.Text != "v1.2 is out" || got.Thread.ChannelID != 12 || got.Thread.Creator != 34 {
+		t.Errorf("unexpected event: %+v", got)
+	}
+	if v := got.Raw.Get("attachments"); v != `[{"title":"notes"}]` {
+		t.Errorf("got raw attachments %q, want them as JSON", v)
+	}
+	if got.Raw.Has("verify_token") || got.Raw.Has("url_callback") {
+		t.Errorf("unexpected raw parameters: %v", got.Raw)
+	}
+}
+
+func TestHandler_dispatch(t *testing.T) {
+	h := NewHandler("secret")
+	var calls []string
+	h.OnThread(func(_ context.Context, e *ThreadEvent) (*Reply, error) {
+		calls = append(calls, fmt.Sprintf("thread %d in channel %d", e.Thread.Id, e.Thread.ChannelID))
+		return nil, nil
+	})
+	h.OnComment(func(_ context.Context, e *CommentEvent) (*Reply, error) {
+		calls = append(calls, fmt.Sprintf("comment %d in thread %d", e.Comment.Id, e.Comment.ThreadID))
+		return nil, nil
+	})
+	h.OnMessage(func(_ context.Context, e *MessageEvent) (*Reply, error) {
+		calls = append(calls, fmt.Sprintf("message %d in conversation %d by %s",
+			e.Message.Id, e.Message.ConversationID, e.Message.CreatorName))
+		return &Reply{Content: "got it"}, nil
+	})
+	for _, tc := range []struct {
+		name  string
+		vals  url.Values
+		want  string // callback call, empty if none
+		reply string
+	}{
+		{
+			name: "thread",
+			vals: url.Values{"event_type": {"thread_added"}, "channel_id": {"3"}, "thread_id": {"12"}},
+			want: "thread 12 in channel 3",
+		},
+		{
+			name: "comment",
+			vals: url.Values{"event_type": {"comment_added"}, "thread_id": {"12"}, "comment_id": {"56"}},
+			want: "comment 56 in thread 12",
+		},
+		{
+			name:  "conversation message",
+			vals:  url.Values{"event_type": {"message_added"}, "conversation_id": {"7"}, "message_id": {"78"}, "user_name": {"Maria"}},
+			want:  "message 78 in conversation 7 by Maria",
+			reply: `{"content":"got it"}` + "\n",
+		},
+		{
+			name: "ping",
+			vals: url.Values{"event_type": {"ping"}},
+		},
+	} {
+		calls = nil
+		tc.vals.Set("verify_token", "secret")
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.vals.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want 200", tc.name, w.Code)
+		}
+		if got := strings.Join(calls, "; "); got != tc.want {
+			t.Errorf("%s: got callback calls %q, want %q", tc.name, got, tc.want)
+		}
+		if got := w.Body.String(); got != tc.reply {
+			t.Errorf("%s: got response body %q, want %q", tc.name, got, tc.reply)
+		}
+	}
+}
+
+func TestHandler_methodNotAllowed(t *testing.T) {
+	h := NewHandler("secret")
+	h.OnComment(func(context.Context, *CommentEvent) (*Reply, error) {
+		t.Error("callback called for GET request")
+		return nil, nil
+	})
+	r := httptest.NewRequest(http.MethodGet, "/?verify_token=secret&thread_id=12&comment_id=56", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Fatalf("got status %d and Allow %q, want 405 and POST", w.Code, w.Header().Get("Allow"))
+	}
+}